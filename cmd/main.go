@@ -1,14 +1,29 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/sangnt1552314/mangadex-tui/internal/api"
+	"github.com/sangnt1552314/mangadex-tui/internal/auth"
+	"github.com/sangnt1552314/mangadex-tui/internal/cache"
+	"github.com/sangnt1552314/mangadex-tui/internal/config"
+	"github.com/sangnt1552314/mangadex-tui/internal/download"
+	"github.com/sangnt1552314/mangadex-tui/internal/graphics"
+	"github.com/sangnt1552314/mangadex-tui/internal/library"
+	"github.com/sangnt1552314/mangadex-tui/internal/progress"
+	"github.com/sangnt1552314/mangadex-tui/internal/readsync"
 	"github.com/sangnt1552314/mangadex-tui/internal/ui"
 )
 
 func main() {
+	offline := flag.Bool("offline", false, "read only from the library and downloaded chapters")
+	flag.Parse()
+
 	if err := os.MkdirAll("storage/logs", 0755); err != nil {
 		panic(fmt.Errorf("failed to create logs directory: %w", err))
 	}
@@ -21,7 +36,91 @@ func main() {
 	defer logFile.Close()
 	log.SetOutput(logFile)
 
-	app := ui.NewApp()
+	configDir, err := config.ConfigDir()
+	if err != nil {
+		panic(err)
+	}
+	authenticator := auth.NewAuthenticator(auth.NewStore(configDir))
+
+	settings, err := config.LoadSettings()
+	if err != nil {
+		panic(fmt.Errorf("failed to load settings: %w", err))
+	}
+
+	cacheDir, err := config.CacheDir()
+	if err != nil {
+		panic(err)
+	}
+	httpCache, err := cache.Open(filepath.Join(cacheDir, "http"), int64(settings.CacheSizeMB)<<20)
+	if err != nil {
+		panic(fmt.Errorf("failed to open cache: %w", err))
+	}
+	defer func() {
+		if err := httpCache.Flush(); err != nil {
+			log.Println("Error saving cache index:", err)
+		}
+	}()
+
+	transport := cache.NewTransport(httpCache, nil)
+	client := api.NewClient(
+		api.WithTimeout(30*time.Second),
+		api.WithTokenSource(authenticator),
+		api.WithTransport(transport),
+	)
+
+	dataDir, err := config.DataDir()
+	if err != nil {
+		panic(err)
+	}
+	lib, err := library.Open(dataDir)
+	if err != nil {
+		panic(fmt.Errorf("failed to open library: %w", err))
+	}
+	readingProgress, err := progress.Open(dataDir)
+	if err != nil {
+		panic(fmt.Errorf("failed to open reading progress: %w", err))
+	}
+	readSync, err := readsync.Open(dataDir, client, authenticator, readingProgress, lib)
+	if err != nil {
+		panic(fmt.Errorf("failed to open read marker queue: %w", err))
+	}
+
+	downloader, err := download.Open(client, settings.DownloadDir, settings.DownloadTemplate)
+	if err != nil {
+		panic(fmt.Errorf("failed to open downloads: %w", err))
+	}
+	downloads, err := download.NewManager(dataDir, client, downloader, settings.DownloadWorkers)
+	if err != nil {
+		panic(fmt.Errorf("failed to open download queue: %w", err))
+	}
+
+	// Ask the terminal what it can draw while we still own it
+	terminal := graphics.Probe(graphics.DefaultProbeTimeout)
+
+	app := ui.NewApp(ui.Dependencies{
+		Client:     client,
+		Auth:       authenticator,
+		Settings:   settings,
+		Cache:      httpCache,
+		Library:    lib,
+		Progress:   readingProgress,
+		Sync:       readSync,
+		Downloader: downloader,
+		Downloads:  downloads,
+		Terminal:   terminal,
+	}, *offline)
+
+	// Stale listings hide network errors from the pages, so the transport
+	// reports them instead
+	transport.SetStaleFunc(func(err error) {
+		app.SetOffline(true)
+	})
+	readSync.SetOfflineFunc(app.Offline)
+
+	go httpCache.Run(app.Context())
+	go client.RunAtHomeReports(app.Context())
+	go readSync.Run(app.Context())
+	go downloads.Run(app.Context())
 
 	if err := app.Run(); err != nil {
 		panic(fmt.Errorf("failed to run application: %w", err))
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/cache"
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+)
+
+const (
+	atHomeReportURL = "https://api.mangadex.network/report"
+
+	// reportQueueSize bounds the reports waiting to be sent. Reports are
+	// best effort, so more are dropped rather than slowing reading down.
+	reportQueueSize = 256
+
+	// reportBatchSize and reportInterval decide when queued reports are sent
+	reportBatchSize = 20
+	reportInterval  = 10 * time.Second
+
+	// reportWorkers is how many reports of a batch are sent at once
+	reportWorkers = 4
+)
+
+// FetchAtHome fetches an image from a MangaDex@Home node and queues a report
+// of how the node did, as the MangaDex@Home spec asks clients to
+func (c *Client) FetchAtHome(ctx context.Context, imageURL string) ([]byte, error) {
+	start := time.Now()
+
+	resp, err := c.FetchURL(ctx, imageURL)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.reportAtHome(imageURL, false, false, 0, start)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.reportAtHome(imageURL, false, false, len(data), start)
+		}
+		return nil, err
+	}
+
+	// Pages read back from disk never reached the node
+	if !cache.IsHit(resp) {
+		cached := strings.HasPrefix(resp.Header.Get("X-Cache"), "HIT")
+		c.reportAtHome(imageURL, true, cached, len(data), start)
+	}
+
+	return data, nil
+}
+
+// reportAtHome queues a report for images served by MangaDex@Home nodes.
+// MangaDex's own servers are not reported.
+func (c *Client) reportAtHome(imageURL string, success, cached bool, size int, start time.Time) {
+	parsed, err := url.Parse(imageURL)
+	if err != nil || parsed.Hostname() == "mangadex.org" || strings.HasSuffix(parsed.Hostname(), ".mangadex.org") {
+		return
+	}
+
+	report := models.AtHomeReport{
+		URL:      imageURL,
+		Success:  success,
+		Cached:   cached,
+		Bytes:    size,
+		Duration: time.Since(start).Milliseconds(),
+	}
+	select {
+	case c.reports <- report:
+	default:
+	}
+}
+
+// RunAtHomeReports sends queued MangaDex@Home reports in batches until ctx
+// is cancelled. The spec takes one report per request, so the reports of a
+// batch are sent a few at a time.
+func (c *Client) RunAtHomeReports(ctx context.Context) {
+	ticker := time.NewTicker(reportInterval)
+	defer ticker.Stop()
+
+	var batch []models.AtHomeReport
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case report := <-c.reports:
+			batch = append(batch, report)
+			if len(batch) < reportBatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+
+		c.sendAtHomeReports(ctx, batch)
+		batch = batch[:0]
+	}
+}
+
+// sendAtHomeReports sends a batch of reports, at most reportWorkers at a
+// time, and returns once all are done
+func (c *Client) sendAtHomeReports(ctx context.Context, batch []models.AtHomeReport) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, reportWorkers)
+	for _, report := range batch {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(report models.AtHomeReport) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := c.sendAtHomeReport(ctx, report); err != nil && ctx.Err() == nil {
+				log.Println("Error reporting to MangaDex@Home:", err)
+			}
+		}(report)
+	}
+	wg.Wait()
+}
+
+func (c *Client) sendAtHomeReport(ctx context.Context, report models.AtHomeReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.atHomeReportURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return newAPIError(resp)
+	}
+	return nil
+}
@@ -1,9 +1,14 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/sangnt1552314/mangadex-tui/internal/models"
@@ -17,59 +22,190 @@ const (
 	Thumbnail512      = 512 // 512px width
 )
 
-type HTTPError struct {
-	StatusCode int
+// TokenSource supplies the bearer token for authenticated requests. An
+// empty token means the request is sent anonymously.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
 }
 
-func (e *HTTPError) Error() string {
-	return fmt.Sprintf("HTTP error: status code %d", e.StatusCode)
+type Client struct {
+	httpClient      *http.Client
+	baseURL         string
+	atHomeReportURL string
+	token           string
+	tokenSource     TokenSource
+	scheduler       *scheduler
+	reports         chan models.AtHomeReport
 }
 
-type Client struct {
-	httpClient *http.Client
-	baseURL    string
-	token      string
+// Option configures a Client
+type Option func(*Client)
+
+// WithBaseURL overrides the MangaDex API base URL
+func WithBaseURL(url string) Option {
+	return func(c *Client) {
+		c.baseURL = url
+	}
+}
+
+// WithAtHomeReportURL overrides where MangaDex@Home reports are sent
+func WithAtHomeReportURL(url string) Option {
+	return func(c *Client) {
+		c.atHomeReportURL = url
+	}
+}
+
+// WithTimeout sets the timeout applied to every request
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.httpClient.Timeout = timeout
+	}
 }
 
-func NewClient() *Client {
-	return &Client{
+// WithTransport sets the round tripper used for requests
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.httpClient.Transport = transport
+	}
+}
+
+// WithToken sets the bearer token sent with API requests
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithTokenSource sets where access tokens come from, taking precedence over WithToken
+func WithTokenSource(source TokenSource) Option {
+	return func(c *Client) {
+		c.tokenSource = source
+	}
+}
+
+// WithMaxRetries sets how many times rate-limited or failed requests are retried
+func WithMaxRetries(retries int) Option {
+	return func(c *Client) {
+		c.scheduler.maxRetries = retries
+	}
+}
+
+func NewClient(opts ...Option) *Client {
+	c := &Client{
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		baseURL: baseURL,
+		baseURL:         baseURL,
+		atHomeReportURL: atHomeReportURL,
+		scheduler:       newScheduler(),
+		reports:         make(chan models.AtHomeReport, reportQueueSize),
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
-func (c *Client) Get(url string) (*http.Response, error) {
-	req, err := http.NewRequest("GET", c.baseURL+url, nil)
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	if c.token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.token)
+	if err := c.authorize(req); err != nil {
+		return nil, err
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, &HTTPError{StatusCode: resp.StatusCode}
+		defer resp.Body.Close()
+		return nil, newAPIError(resp)
 	}
 
 	return resp, nil
 }
 
-func (c *Client) Post(url string, body interface{}) (*http.Response, error) {
-	req, err := http.NewRequest("POST", c.baseURL+url, nil)
-	if err != nil {
-		return nil, err
+// authorize sets the Authorization header when a token is available
+func (c *Client) authorize(req *http.Request) error {
+	token := c.token
+	if c.tokenSource != nil {
+		var err error
+		if token, err = c.tokenSource.Token(req.Context()); err != nil {
+			return fmt.Errorf("failed to get access token: %w", err)
+		}
 	}
 
-	if c.token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.token)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// RateLimitState reports whether requests are currently throttled
+func (c *Client) RateLimitState() RateLimitState {
+	return c.scheduler.State()
+}
+
+// do sends an API request through the rate limit scheduler, retrying
+// 429 and 5xx responses with jittered exponential backoff.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	path := req.URL.Path
+
+	for attempt := 0; ; attempt++ {
+		if err := c.scheduler.wait(ctx, req.Method, path); err != nil {
+			return nil, err
+		}
+
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		c.scheduler.observe(req.Method, path, resp.Header)
+
+		if !shouldRetry(resp.StatusCode) || attempt >= c.scheduler.maxRetries {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		retryAt := time.Now().Add(retryDelay(attempt))
+		if resp.StatusCode == http.StatusTooManyRequests {
+			if at := parseRetryAfter(resp.Header); at.After(retryAt) {
+				retryAt = at
+			}
+			c.scheduler.block(req.Method, path, retryAt)
+		} else {
+			c.scheduler.throttle(path, retryAt)
+		}
+
+		log.Printf("Request %s %s failed with status %d, retrying in %s", req.Method, path, resp.StatusCode, time.Until(retryAt).Round(time.Second))
+		if err := sleep(ctx, time.Until(retryAt)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// FetchURL performs a GET request against an absolute URL outside the API,
+// such as cover uploads or MangaDex@Home image servers.
+func (c *Client) FetchURL(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
 	}
 
 	resp, err := c.httpClient.Do(req)
@@ -78,23 +214,109 @@ func (c *Client) Post(url string, body interface{}) (*http.Response, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, &HTTPError{StatusCode: resp.StatusCode}
+		defer resp.Body.Close()
+		return nil, newAPIError(resp)
 	}
 
 	return resp, nil
 }
 
-func GetManga(params models.MangaQueryParams) ([]models.Manga, error) {
-	client := NewClient()
+// Do sends a request to the API. A non-nil body is sent as JSON and a
+// non-nil out receives the decoded response. 200, 201 and 204 count as
+// success; anything else is returned as an *APIError.
+func (c *Client) Do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
 
-	url := getMangaApiUrl(params)
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request body: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if err := c.authorize(req); err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+	case http.StatusNoContent:
+		return nil
+	default:
+		return newAPIError(resp)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) Post(ctx context.Context, path string, body, out interface{}) error {
+	return c.Do(ctx, http.MethodPost, path, nil, body, out)
+}
+
+func (c *Client) Put(ctx context.Context, path string, body, out interface{}) error {
+	return c.Do(ctx, http.MethodPut, path, nil, body, out)
+}
 
-	resp, err := client.Get(url)
+func (c *Client) Delete(ctx context.Context, path string, out interface{}) error {
+	return c.Do(ctx, http.MethodDelete, path, nil, nil, out)
+}
 
+func (c *Client) GetManga(ctx context.Context, params models.MangaQueryParams) ([]models.Manga, error) {
+	page, err := c.GetMangaPage(ctx, params)
 	if err != nil {
 		return nil, err
 	}
 
+	if len(page.Items) == 0 {
+		return nil, fmt.Errorf("no manga found: %w", ErrNoResults)
+	}
+
+	return page.Items, nil
+}
+
+// GetMangaPage returns one page of manga along with the total result count
+func (c *Client) GetMangaPage(ctx context.Context, params models.MangaQueryParams) (*models.Page[models.Manga], error) {
+	url := getMangaApiUrl(params)
+
+	resp, err := c.Get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	var mangaList models.MangaListResponse
@@ -106,19 +328,18 @@ func GetManga(params models.MangaQueryParams) ([]models.Manga, error) {
 		return nil, fmt.Errorf("API error: %s", mangaList.Result)
 	}
 
-	if len(mangaList.Data) == 0 {
-		return nil, fmt.Errorf("no manga found")
-	}
-
-	return mangaList.Data, nil
+	return &models.Page[models.Manga]{
+		Items:  mangaList.Data,
+		Limit:  mangaList.Limit,
+		Offset: mangaList.Offset,
+		Total:  mangaList.Total,
+	}, nil
 }
 
-func GetChapters(params models.ChapterQueryParams) ([]models.Chapter, error) {
-	client := NewClient()
-
+func (c *Client) GetChapters(ctx context.Context, params models.ChapterQueryParams) ([]models.Chapter, error) {
 	url := getChapterApiUrl(params)
 
-	resp, err := client.Get(url)
+	resp, err := c.Get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -134,18 +355,16 @@ func GetChapters(params models.ChapterQueryParams) ([]models.Chapter, error) {
 	}
 
 	if len(chapterList.Data) == 0 {
-		return nil, fmt.Errorf("no chapters found")
+		return nil, fmt.Errorf("no chapters found: %w", ErrNoResults)
 	}
 
 	return chapterList.Data, nil
 }
 
-func GetChapterListResponse(params models.ChapterQueryParams) (*models.ChapterListResponse, error) {
-	client := NewClient()
-
+func (c *Client) GetChapterListResponse(ctx context.Context, params models.ChapterQueryParams) (*models.ChapterListResponse, error) {
 	url := getChapterApiUrl(params)
 
-	resp, err := client.Get(url)
+	resp, err := c.Get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -163,12 +382,10 @@ func GetChapterListResponse(params models.ChapterQueryParams) (*models.ChapterLi
 	return &chapterList, nil
 }
 
-func GetMangaCover(mangaID string) (*models.CoverListResponse, error) {
-	client := NewClient()
-
-	url := fmt.Sprintf("/cover?manga[]=%s", mangaID)
+func (c *Client) GetMangaCover(ctx context.Context, mangaID string) (*models.CoverListResponse, error) {
+	url := newQueryBuilder().addAll("manga", []string{mangaID}).URL("/cover")
 
-	resp, err := client.Get(url)
+	resp, err := c.Get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -186,85 +403,127 @@ func GetMangaCover(mangaID string) (*models.CoverListResponse, error) {
 	return &coverList, nil
 }
 
-func GetCoverURL(mangaID string, filename string, size int) string {
-	url := fmt.Sprintf("%s/%s/%s", coverBaseURL, mangaID, filename)
+func (c *Client) GetTags(ctx context.Context) ([]models.Tag, error) {
+	resp, err := c.Get(ctx, "/manga/tag")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-	switch size {
-	case Thumbnail256:
-		return url + ".256.jpg"
-	case Thumbnail512:
-		return url + ".512.jpg"
-	default:
-		return url
+	var tagList models.TagListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tagList); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
-}
 
-func GetChapterImageResponse(chapterId string) (*models.ImageResponse, error) {
-	client := NewClient()
+	if tagList.Result != "ok" {
+		return nil, fmt.Errorf("API error: %s", tagList.Result)
+	}
 
-	url := fmt.Sprintf("/at-home/server/%s", chapterId)
+	return tagList.Data, nil
+}
 
-	resp, err := client.Get(url)
+func (c *Client) SearchAuthors(ctx context.Context, name string, limit int) ([]models.Author, error) {
+	url := newQueryBuilder().
+		set("name", name).
+		setInt("limit", limit).
+		URL("/author")
 
+	resp, err := c.Get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	var imageResponse models.ImageResponse
-	if err := json.NewDecoder(resp.Body).Decode(&imageResponse); err != nil {
+	var authorList models.AuthorListResponse
+	if err := json.NewDecoder(resp.Body).Decode(&authorList); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	if imageResponse.Result != "ok" {
-		return nil, fmt.Errorf("API error: %s", imageResponse.Result)
+	if authorList.Result != "ok" {
+		return nil, fmt.Errorf("API error: %s", authorList.Result)
 	}
 
-	return &imageResponse, nil
+	return authorList.Data, nil
 }
 
-func getMangaApiUrl(params models.MangaQueryParams) string {
-	queryParams := fmt.Sprintf("?limit=%d", params.Limit)
-
-	for orderKey, orderDir := range params.Order {
-		queryParams += fmt.Sprintf("&order[%s]=%s", orderKey, orderDir)
+// GetReadMarkers returns the IDs of the chapters of a manga the logged in
+// user has read
+func (c *Client) GetReadMarkers(ctx context.Context, mangaID string) ([]string, error) {
+	var markers models.ReadMarkersResponse
+	if err := c.Do(ctx, http.MethodGet, fmt.Sprintf("/manga/%s/read", mangaID), nil, nil, &markers); err != nil {
+		return nil, err
 	}
 
-	for _, rating := range params.ContentRating {
-		queryParams += fmt.Sprintf("&contentRating[]=%s", rating)
+	if markers.Result != "ok" {
+		return nil, fmt.Errorf("API error: %s", markers.Result)
 	}
 
-	for _, include := range params.Includes {
-		queryParams += fmt.Sprintf("&includes[]=%s", include)
+	return markers.Data, nil
+}
+
+// GetReadMarkersBatch returns read chapter IDs for several manga at once,
+// keyed by manga ID
+func (c *Client) GetReadMarkersBatch(ctx context.Context, mangaIDs []string) (map[string][]string, error) {
+	query := newQueryBuilder().
+		addAll("ids", mangaIDs).
+		setBool("grouped", true).
+		values
+
+	var markers models.GroupedReadMarkersResponse
+	if err := c.Do(ctx, http.MethodGet, "/manga/read", query, nil, &markers); err != nil {
+		return nil, err
 	}
 
-	if params.HasChapters {
-		queryParams += "&hasAvailableChapters=true"
+	if markers.Result != "ok" {
+		return nil, fmt.Errorf("API error: %s", markers.Result)
 	}
 
-	url := "/manga" + queryParams
+	return markers.Data, nil
+}
 
-	return url
+// UpdateReadMarkers marks chapters of a manga as read or unread
+func (c *Client) UpdateReadMarkers(ctx context.Context, mangaID string, update models.ReadMarkersUpdate) error {
+	if update.ChapterIdsRead == nil {
+		update.ChapterIdsRead = []string{}
+	}
+	if update.ChapterIdsUnread == nil {
+		update.ChapterIdsUnread = []string{}
+	}
+
+	return c.Post(ctx, fmt.Sprintf("/manga/%s/read", mangaID), update, nil)
 }
 
-func getChapterApiUrl(params models.ChapterQueryParams) string {
-	queryParams := fmt.Sprintf("?limit=%d&offset=%d", params.Limit, params.Offset)
+func GetCoverURL(mangaID string, filename string, size int) string {
+	url := fmt.Sprintf("%s/%s/%s", coverBaseURL, mangaID, filename)
 
-	if len(params.Ids) > 0 {
-		for _, id := range params.Ids {
-			queryParams += fmt.Sprintf("&ids[]=%s", id)
-		}
+	switch size {
+	case Thumbnail256:
+		return url + ".256.jpg"
+	case Thumbnail512:
+		return url + ".512.jpg"
+	default:
+		return url
 	}
+}
+
+func (c *Client) GetChapterImageResponse(ctx context.Context, chapterId string) (*models.ImageResponse, error) {
+	url := fmt.Sprintf("/at-home/server/%s", chapterId)
 
-	if params.MangaId != "" {
-		queryParams += fmt.Sprintf("&manga=%s", params.MangaId)
+	resp, err := c.Get(ctx, url)
+
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	for _, lang := range params.TranslatedLanguage {
-		queryParams += fmt.Sprintf("&translatedLanguage[]=%s", lang)
+	var imageResponse models.ImageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&imageResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	url := "/chapter" + queryParams
+	if imageResponse.Result != "ok" {
+		return nil, fmt.Errorf("API error: %s", imageResponse.Result)
+	}
 
-	return url
+	return &imageResponse, nil
 }
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBodySize bounds how much of an error response is read
+const maxErrorBodySize = 64 << 10
+
+// ErrNoResults is returned when a list endpoint returns no data
+var ErrNoResults = errors.New("no results")
+
+// ErrorDetail is a single entry of a MangaDex error response
+type ErrorDetail struct {
+	ID      string          `json:"id"`
+	Status  int             `json:"status"`
+	Title   string          `json:"title"`
+	Detail  string          `json:"detail"`
+	Context json.RawMessage `json:"context"`
+}
+
+// APIError is returned for any non-success response. Errors holds the
+// decoded MangaDex error envelope when the body contained one.
+type APIError struct {
+	StatusCode int
+	RequestID  string
+	Errors     []ErrorDetail
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("API error: status code %d", e.StatusCode)
+	if len(e.Errors) > 0 {
+		msg += ": " + e.Message()
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" (request %s)", e.RequestID)
+	}
+	return msg
+}
+
+// Message returns the human readable details of the error
+func (e *APIError) Message() string {
+	if len(e.Errors) == 0 {
+		return http.StatusText(e.StatusCode)
+	}
+
+	var parts []string
+	for _, detail := range e.Errors {
+		switch {
+		case detail.Detail != "":
+			parts = append(parts, detail.Detail)
+		case detail.Title != "":
+			parts = append(parts, detail.Title)
+		}
+	}
+	if len(parts) == 0 {
+		return http.StatusText(e.StatusCode)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// errorResponse is the MangaDex error envelope
+type errorResponse struct {
+	Result string        `json:"result"`
+	Errors []ErrorDetail `json:"errors"`
+}
+
+// newAPIError builds an APIError from a failed response. The body is read
+// but not closed.
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get("X-Request-ID"),
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err != nil || len(body) == 0 {
+		return apiErr
+	}
+
+	var envelope errorResponse
+	if err := json.Unmarshal(body, &envelope); err == nil {
+		apiErr.Errors = envelope.Errors
+	}
+
+	return apiErr
+}
+
+func hasStatus(err error, statusCode int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
+
+// IsNotFound reports whether err is a 404 from MangaDex
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsRateLimited reports whether err is a 429 from MangaDex
+func IsRateLimited(err error) bool {
+	return hasStatus(err, http.StatusTooManyRequests)
+}
+
+// IsUnauthorized reports whether err is a 401 or 403 from MangaDex
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized) || hasStatus(err, http.StatusForbidden)
+}
+
+// IsNetworkError reports whether err means MangaDex could not be reached at
+// all, as opposed to an error response or a cancelled request
+func IsNetworkError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+)
+
+// timeFormat is the date-time format MangaDex expects in query filters
+const timeFormat = "2006-01-02T15:04:05"
+
+// queryBuilder builds escaped query strings on top of url.Values. Sort keys
+// are kept apart and encoded in the order given, since their order sets the
+// sort precedence.
+type queryBuilder struct {
+	values url.Values
+	order  []models.Order
+}
+
+func newQueryBuilder() *queryBuilder {
+	return &queryBuilder{values: url.Values{}}
+}
+
+func (q *queryBuilder) set(key, value string) *queryBuilder {
+	if value != "" {
+		q.values.Set(key, value)
+	}
+	return q
+}
+
+func (q *queryBuilder) setInt(key string, value int) *queryBuilder {
+	if value > 0 {
+		q.values.Set(key, strconv.Itoa(value))
+	}
+	return q
+}
+
+// setBool only emits the flag when it is true
+func (q *queryBuilder) setBool(key string, value bool) *queryBuilder {
+	if value {
+		q.values.Set(key, "true")
+	}
+	return q
+}
+
+// setOptionalBool emits the flag whenever it is set, true or false
+func (q *queryBuilder) setOptionalBool(key string, value *bool) *queryBuilder {
+	if value != nil {
+		q.values.Set(key, strconv.FormatBool(*value))
+	}
+	return q
+}
+
+func (q *queryBuilder) setTime(key string, value time.Time) *queryBuilder {
+	if !value.IsZero() {
+		q.values.Set(key, value.UTC().Format(timeFormat))
+	}
+	return q
+}
+
+// addAll appends values under the array form of key, e.g. "ids[]"
+func (q *queryBuilder) addAll(key string, values []string) *queryBuilder {
+	for _, value := range values {
+		if value != "" {
+			q.values.Add(key+"[]", value)
+		}
+	}
+	return q
+}
+
+func (q *queryBuilder) orderBy(order []models.Order) *queryBuilder {
+	for _, o := range order {
+		if o.Field != "" && o.Direction != "" {
+			q.order = append(q.order, o)
+		}
+	}
+	return q
+}
+
+// Encode returns the query string without the leading "?". Regular
+// parameters are sorted by key; sort keys follow in precedence order.
+func (q *queryBuilder) Encode() string {
+	var sb strings.Builder
+	sb.WriteString(q.values.Encode())
+
+	for _, o := range q.order {
+		if sb.Len() > 0 {
+			sb.WriteByte('&')
+		}
+		sb.WriteString(url.QueryEscape("order[" + o.Field + "]"))
+		sb.WriteByte('=')
+		sb.WriteString(url.QueryEscape(o.Direction))
+	}
+
+	return sb.String()
+}
+
+// URL joins path and the encoded query
+func (q *queryBuilder) URL(path string) string {
+	query := q.Encode()
+	if query == "" {
+		return path
+	}
+	return path + "?" + query
+}
+
+func getMangaApiUrl(params models.MangaQueryParams) string {
+	return newQueryBuilder().
+		setInt("limit", params.Limit).
+		setInt("offset", params.Offset).
+		set("title", params.Title).
+		set("authorOrArtist", params.AuthorOrArtist).
+		addAll("authors", params.Authors).
+		addAll("artists", params.Artists).
+		setInt("year", params.Year).
+		addAll("includedTags", params.IncludedTags).
+		set("includedTagsMode", params.IncludedTagsMode).
+		addAll("excludedTags", params.ExcludedTags).
+		set("excludedTagsMode", params.ExcludedTagsMode).
+		addAll("status", params.Status).
+		addAll("originalLanguage", params.OriginalLanguage).
+		addAll("excludedOriginalLanguage", params.ExcludedOriginalLanguage).
+		addAll("availableTranslatedLanguage", params.AvailableTranslatedLanguage).
+		addAll("publicationDemographic", params.PublicationDemographic).
+		addAll("ids", params.Ids).
+		addAll("contentRating", params.ContentRating).
+		setTime("createdAtSince", params.CreatedAtSince).
+		setTime("updatedAtSince", params.UpdatedAtSince).
+		addAll("includes", params.Includes).
+		setBool("hasAvailableChapters", params.HasChapters).
+		set("group", params.Group).
+		orderBy(params.Order).
+		URL("/manga")
+}
+
+func getChapterApiUrl(params models.ChapterQueryParams) string {
+	return newQueryBuilder().
+		setInt("limit", params.Limit).
+		setInt("offset", params.Offset).
+		addAll("ids", params.Ids).
+		set("title", params.Title).
+		addAll("groups", params.Groups).
+		addAll("uploader", params.Uploader).
+		set("manga", params.MangaId).
+		addAll("volume", params.Volume).
+		addAll("chapter", params.Chapter).
+		addAll("translatedLanguage", params.TranslatedLanguage).
+		addAll("originalLanguage", params.OriginalLanguage).
+		addAll("excludedOriginalLanguage", params.ExcludedOriginalLanguage).
+		addAll("contentRating", params.ContentRating).
+		addAll("excludedGroups", params.ExcludedGroups).
+		addAll("excludedUploaders", params.ExcludedUploaders).
+		setOptionalBool("includeFutureUpdates", params.IncludeFutureUpdates).
+		setOptionalBool("includeEmptyPages", params.IncludeEmptyPages).
+		setOptionalBool("includeFuturePublishAt", params.IncludeFuturePublishAt).
+		setOptionalBool("includeExternalUrl", params.IncludeExternalUrl).
+		setTime("createdAtSince", params.CreatedAtSince).
+		setTime("updatedAtSince", params.UpdatedAtSince).
+		setTime("publishAtSince", params.PublishAtSince).
+		addAll("includes", params.Includes).
+		orderBy(params.Order).
+		URL("/chapter")
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+)
+
+func TestGetMangaApiUrl(t *testing.T) {
+	tests := []struct {
+		name   string
+		params models.MangaQueryParams
+		want   string
+	}{
+		{
+			name:   "empty",
+			params: models.MangaQueryParams{},
+			want:   "/manga",
+		},
+		{
+			name: "escaped title",
+			params: models.MangaQueryParams{
+				Limit: 20,
+				Title: "Spy & Family 2",
+			},
+			want: "/manga?limit=20&title=Spy+%26+Family+2",
+		},
+		{
+			name: "arrays",
+			params: models.MangaQueryParams{
+				Includes:      []string{"cover_art", "author"},
+				ContentRating: []string{"safe", "suggestive"},
+				Ids:           []string{"a", ""},
+			},
+			want: "/manga?contentRating%5B%5D=safe&contentRating%5B%5D=suggestive&ids%5B%5D=a" +
+				"&includes%5B%5D=cover_art&includes%5B%5D=author",
+		},
+		{
+			name: "order keeps precedence",
+			params: models.MangaQueryParams{
+				Offset: 40,
+				Order: []models.Order{
+					{Field: "latestUploadedChapter", Direction: "desc"},
+					{Field: "title", Direction: "asc"},
+					{Field: "relevance", Direction: ""},
+				},
+			},
+			want: "/manga?offset=40&order%5BlatestUploadedChapter%5D=desc&order%5Btitle%5D=asc",
+		},
+		{
+			name: "dates and flags",
+			params: models.MangaQueryParams{
+				UpdatedAtSince: time.Date(2024, 3, 1, 12, 30, 0, 0, time.FixedZone("ICT", 7*60*60)),
+				HasChapters:    true,
+			},
+			want: "/manga?hasAvailableChapters=true&updatedAtSince=2024-03-01T05%3A30%3A00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getMangaApiUrl(tt.params); got != tt.want {
+				t.Errorf("getMangaApiUrl() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetChapterApiUrl(t *testing.T) {
+	yes, no := true, false
+
+	tests := []struct {
+		name   string
+		params models.ChapterQueryParams
+		want   string
+	}{
+		{
+			name: "manga feed",
+			params: models.ChapterQueryParams{
+				Limit:              100,
+				MangaId:            "f9c33607-9180-4ba6-b85c-e4b5faee7192",
+				TranslatedLanguage: []string{"en"},
+				ContentRating:      []string{"safe", "erotica"},
+				Includes:           []string{"scanlation_group"},
+				Order: []models.Order{
+					{Field: "volume", Direction: "desc"},
+					{Field: "chapter", Direction: "desc"},
+				},
+			},
+			want: "/chapter?contentRating%5B%5D=safe&contentRating%5B%5D=erotica&includes%5B%5D=scanlation_group" +
+				"&limit=100&manga=f9c33607-9180-4ba6-b85c-e4b5faee7192&translatedLanguage%5B%5D=en" +
+				"&order%5Bvolume%5D=desc&order%5Bchapter%5D=desc",
+		},
+		{
+			name: "optional flags",
+			params: models.ChapterQueryParams{
+				Title:                "Side story: A & B",
+				IncludeEmptyPages:    &no,
+				IncludeExternalUrl:   &yes,
+				IncludeFutureUpdates: nil,
+			},
+			want: "/chapter?includeEmptyPages=false&includeExternalUrl=true&title=Side+story%3A+A+%26+B",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getChapterApiUrl(tt.params); got != tt.want {
+				t.Errorf("getChapterApiUrl() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
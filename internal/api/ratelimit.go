@@ -0,0 +1,263 @@
+package api
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	globalRequestsPerSecond = 5
+	defaultMaxRetries       = 3
+	baseRetryDelay          = 500 * time.Millisecond
+	maxRetryDelay           = 30 * time.Second
+)
+
+// endpointLimit describes a MangaDex per-endpoint rate limit. The path is
+// matched segment by segment, with {id} standing for any one segment.
+type endpointLimit struct {
+	method string
+	path   string
+	limit  int
+	per    time.Duration
+}
+
+// Documented per-endpoint limits, on top of the global limit.
+// See https://api.mangadex.org/docs/2-limitations/
+var endpointLimits = []endpointLimit{
+	{method: http.MethodGet, path: "/at-home/server/{id}", limit: 40, per: time.Minute},
+	{method: http.MethodPost, path: "/auth/login", limit: 30, per: time.Hour},
+	{method: http.MethodPost, path: "/auth/refresh", limit: 60, per: time.Hour},
+	{method: http.MethodPut, path: "/chapter/{id}", limit: 10, per: time.Minute},
+	{method: http.MethodDelete, path: "/chapter/{id}", limit: 10, per: time.Minute},
+	{method: http.MethodPost, path: "/manga", limit: 10, per: time.Minute},
+	{method: http.MethodPut, path: "/manga/{id}", limit: 10, per: time.Minute},
+	{method: http.MethodDelete, path: "/manga/{id}", limit: 10, per: time.Minute},
+	{method: http.MethodPost, path: "/report", limit: 10, per: time.Minute},
+	{method: http.MethodPost, path: "/captcha/solve", limit: 10, per: time.Minute},
+}
+
+// matches reports whether a request path is the limit's endpoint
+func (l endpointLimit) matches(path string) bool {
+	want := strings.Split(strings.Trim(l.path, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	if len(want) != len(got) {
+		return false
+	}
+	for i, segment := range want {
+		if got[i] == "" || (segment != "{id}" && segment != got[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// RateLimitState describes whether requests are currently being held back
+type RateLimitState struct {
+	Endpoint string
+	RetryAt  time.Time
+}
+
+// Throttled reports whether requests are waiting for a rate limit to reset
+func (s RateLimitState) Throttled() bool {
+	return !s.RetryAt.IsZero() && time.Now().Before(s.RetryAt)
+}
+
+// RetryIn returns how long until requests resume
+func (s RateLimitState) RetryIn() time.Duration {
+	if !s.Throttled() {
+		return 0
+	}
+	return time.Until(s.RetryAt)
+}
+
+// bucket is a token bucket refilled continuously at limit/per
+type bucket struct {
+	capacity     float64
+	tokens       float64
+	rate         float64
+	last         time.Time
+	blockedUntil time.Time
+}
+
+func newBucket(limit int, per time.Duration) *bucket {
+	return &bucket{
+		capacity: float64(limit),
+		tokens:   float64(limit),
+		rate:     float64(limit) / per.Seconds(),
+		last:     time.Now(),
+	}
+}
+
+// reserve takes a token and returns how long the caller must wait before
+// using it.
+func (b *bucket) reserve(now time.Time) time.Duration {
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	if blocked := b.blockedUntil.Sub(now); blocked > wait {
+		wait = blocked
+	}
+	return wait
+}
+
+// scheduler spaces requests to stay within MangaDex rate limits
+type scheduler struct {
+	mu         sync.Mutex
+	global     *bucket
+	endpoints  map[string]*bucket
+	maxRetries int
+	state      RateLimitState
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		global:     newBucket(globalRequestsPerSecond, time.Second),
+		endpoints:  make(map[string]*bucket),
+		maxRetries: defaultMaxRetries,
+	}
+}
+
+// endpointKey returns the per-endpoint limit bucket key for a request, or
+// an empty string if only the global limit applies.
+func endpointKey(method, path string) (string, endpointLimit) {
+	for _, limit := range endpointLimits {
+		if limit.method == method && limit.matches(path) {
+			return limit.method + " " + limit.path, limit
+		}
+	}
+	return "", endpointLimit{}
+}
+
+// wait blocks until a request to method/path may be sent
+func (s *scheduler) wait(ctx context.Context, method, path string) error {
+	s.mu.Lock()
+	now := time.Now()
+	delay := s.global.reserve(now)
+
+	if key, limit := endpointKey(method, path); key != "" {
+		b, ok := s.endpoints[key]
+		if !ok {
+			b = newBucket(limit.limit, limit.per)
+			s.endpoints[key] = b
+		}
+		if d := b.reserve(now); d > delay {
+			delay = d
+		}
+	}
+	s.mu.Unlock()
+
+	return sleep(ctx, delay)
+}
+
+// observe updates the buckets from the X-RateLimit-* response headers
+func (s *scheduler) observe(method, path string, header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil || remaining > 0 {
+		return
+	}
+
+	retryAt := parseRetryAfter(header)
+	if retryAt.IsZero() {
+		return
+	}
+
+	s.block(method, path, retryAt)
+}
+
+// block holds back requests to method/path until retryAt
+func (s *scheduler) block(method, path string, retryAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := s.global
+	if key, limit := endpointKey(method, path); key != "" {
+		if _, ok := s.endpoints[key]; !ok {
+			s.endpoints[key] = newBucket(limit.limit, limit.per)
+		}
+		b = s.endpoints[key]
+	}
+	if retryAt.After(b.blockedUntil) {
+		b.blockedUntil = retryAt
+	}
+
+	s.setState(RateLimitState{Endpoint: path, RetryAt: retryAt})
+}
+
+func (s *scheduler) setState(state RateLimitState) {
+	if state.RetryAt.After(s.state.RetryAt) {
+		s.state = state
+	}
+}
+
+func (s *scheduler) throttle(path string, retryAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.setState(RateLimitState{Endpoint: path, RetryAt: retryAt})
+}
+
+func (s *scheduler) State() RateLimitState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state
+}
+
+// retryDelay returns the jittered exponential backoff delay for an attempt
+func retryDelay(attempt int) time.Duration {
+	delay := baseRetryDelay * time.Duration(1<<attempt)
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	// Full jitter in [delay/2, delay)
+	half := int64(delay / 2)
+	return time.Duration(half + rand.Int63n(half))
+}
+
+// shouldRetry reports whether a response status is worth retrying
+func shouldRetry(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// parseRetryAfter reads X-RateLimit-Retry-After (a unix timestamp) or the
+// standard Retry-After header (seconds).
+func parseRetryAfter(header http.Header) time.Time {
+	if v := header.Get("X-RateLimit-Retry-After"); v != "" {
+		if ts, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(ts, 0)
+		}
+	}
+	if v := header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Now().Add(time.Duration(secs) * time.Second)
+		}
+	}
+	return time.Time{}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestEndpointKey(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodPost, "/manga", "POST /manga"},
+		{http.MethodPost, "/manga/", "POST /manga"},
+		{http.MethodPost, "/manga/f9c33607-9180-4ba6-b85c-e4b5faee7192/read", ""},
+		{http.MethodPut, "/manga/f9c33607-9180-4ba6-b85c-e4b5faee7192", "PUT /manga/{id}"},
+		{http.MethodGet, "/manga", ""},
+		{http.MethodDelete, "/chapter/a54c491c-8e4c-4e97-8873-5f79e59da210", "DELETE /chapter/{id}"},
+		{http.MethodPost, "/chapter/a54c491c-8e4c-4e97-8873-5f79e59da210/read", ""},
+		{http.MethodGet, "/at-home/server/a54c491c-8e4c-4e97-8873-5f79e59da210", "GET /at-home/server/{id}"},
+		{http.MethodGet, "/at-home/server/", ""},
+		{http.MethodPost, "/auth/login", "POST /auth/login"},
+	}
+
+	for _, tt := range tests {
+		if got, _ := endpointKey(tt.method, tt.path); got != tt.want {
+			t.Errorf("endpointKey(%s, %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
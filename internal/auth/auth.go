@@ -0,0 +1,315 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	tokenURL  = "https://auth.mangadex.org/realms/mangadex/protocol/openid-connect/token"
+	logoutURL = "https://auth.mangadex.org/realms/mangadex/protocol/openid-connect/logout"
+
+	// refreshMargin is how long before expiry an access token is refreshed
+	refreshMargin = time.Minute
+)
+
+// ErrSessionExpired is returned when the refresh token is no longer accepted
+// and the user has to log in again
+var ErrSessionExpired = errors.New("session expired, please log in again")
+
+// Credentials are the inputs of the OAuth2 password grant. Client ID and
+// secret come from a MangaDex personal API client.
+type Credentials struct {
+	ClientID     string
+	ClientSecret string
+	Username     string
+	Password     string
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	RefreshExpiresIn int    `json:"refresh_expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// refreshCall is a token refresh in flight. Callers asking for a token
+// meanwhile wait for it and share its result.
+type refreshCall struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// Authenticator performs the MangaDex login and hands out access tokens,
+// refreshing them shortly before they expire.
+type Authenticator struct {
+	mu          sync.Mutex
+	httpClient  *http.Client
+	store       *Store
+	session     *Session
+	accessToken string
+	expiresAt   time.Time
+	refresh     *refreshCall
+}
+
+func NewAuthenticator(store *Store) *Authenticator {
+	a := &Authenticator{
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		store: store,
+	}
+
+	session, err := store.Load()
+	if err != nil {
+		log.Println("Error loading saved session:", err)
+	}
+	switch {
+	case session == nil || session.RefreshToken == "":
+	case session.Expired(time.Now()):
+		log.Println("Saved session has expired")
+		if err := store.Clear(); err != nil {
+			log.Println("Error clearing expired session:", err)
+		}
+	default:
+		a.session = session
+	}
+
+	return a
+}
+
+// LoggedIn reports whether a session is available. A session whose refresh
+// token has run out counts as logged out.
+func (a *Authenticator) LoggedIn() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.session != nil && !a.session.Expired(time.Now())
+}
+
+// Username returns the name of the logged in user
+func (a *Authenticator) Username() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.session == nil {
+		return ""
+	}
+	return a.session.Username
+}
+
+// Login performs the password grant and persists the refresh token
+func (a *Authenticator) Login(ctx context.Context, creds Credentials) error {
+	form := url.Values{
+		"grant_type":    {"password"},
+		"username":      {creds.Username},
+		"password":      {creds.Password},
+		"client_id":     {creds.ClientID},
+		"client_secret": {creds.ClientSecret},
+	}
+
+	token, err := a.requestToken(ctx, form)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.session = &Session{
+		Username:     creds.Username,
+		ClientID:     creds.ClientID,
+		ClientSecret: creds.ClientSecret,
+	}
+	a.apply(token)
+
+	return a.store.Save(a.session)
+}
+
+// Logout ends the session on the server, best effort, and forgets it locally
+func (a *Authenticator) Logout(ctx context.Context) error {
+	a.mu.Lock()
+	session := a.session
+	a.session = nil
+	a.accessToken = ""
+	a.expiresAt = time.Time{}
+	a.mu.Unlock()
+
+	if session != nil {
+		form := url.Values{
+			"client_id":     {session.ClientID},
+			"client_secret": {session.ClientSecret},
+			"refresh_token": {session.RefreshToken},
+		}
+		if err := a.post(ctx, logoutURL, form, nil); err != nil {
+			log.Println("Error ending session on server:", err)
+		}
+	}
+
+	return a.store.Clear()
+}
+
+// Token returns a valid access token, refreshing it if it is about to
+// expire. It returns an empty token when nobody is logged in. The refresh
+// runs without holding the lock, so LoggedIn and Username stay quick, and
+// concurrent callers share one refresh.
+func (a *Authenticator) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	if a.session == nil {
+		a.mu.Unlock()
+		return "", nil
+	}
+	if a.accessToken != "" && time.Until(a.expiresAt) > refreshMargin {
+		token := a.accessToken
+		a.mu.Unlock()
+		return token, nil
+	}
+	if a.session.Expired(time.Now()) {
+		a.expireLocked()
+		a.mu.Unlock()
+		return "", ErrSessionExpired
+	}
+
+	call := a.refresh
+	if call == nil {
+		call = &refreshCall{done: make(chan struct{})}
+		a.refresh = call
+		go a.runRefresh(call, *a.session)
+	}
+	a.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// runRefresh trades the refresh token of session for a new access token.
+// It runs on its own, so a caller giving up does not cancel it for the
+// others waiting.
+func (a *Authenticator) runRefresh(call *refreshCall, session Session) {
+	defer close(call.done)
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {session.RefreshToken},
+		"client_id":     {session.ClientID},
+		"client_secret": {session.ClientSecret},
+	}
+	token, err := a.requestToken(context.Background(), form)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.refresh = nil
+
+	// A logout or a new login while refreshing wins over the result
+	if a.session == nil || a.session.RefreshToken != session.RefreshToken {
+		call.token = a.accessToken
+		return
+	}
+
+	if errors.Is(err, ErrSessionExpired) {
+		a.expireLocked()
+		call.err = err
+		return
+	}
+	if err != nil {
+		call.err = err
+		return
+	}
+
+	a.apply(token)
+	if err := a.store.Save(a.session); err != nil {
+		log.Println("Error saving refreshed session:", err)
+	}
+	call.token = a.accessToken
+}
+
+// expireLocked forgets a session the server no longer accepts; the caller
+// holds the lock
+func (a *Authenticator) expireLocked() {
+	a.session = nil
+	a.accessToken = ""
+	a.expiresAt = time.Time{}
+	if err := a.store.Clear(); err != nil {
+		log.Println("Error clearing expired session:", err)
+	}
+}
+
+// apply stores a token response; the caller holds the lock
+func (a *Authenticator) apply(token *tokenResponse) {
+	now := time.Now()
+	a.accessToken = token.AccessToken
+	a.expiresAt = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+	if token.RefreshToken != "" {
+		a.session.RefreshToken = token.RefreshToken
+	}
+	if token.RefreshExpiresIn > 0 {
+		a.session.RefreshExpiresAt = now.Add(time.Duration(token.RefreshExpiresIn) * time.Second)
+	}
+}
+
+func (a *Authenticator) requestToken(ctx context.Context, form url.Values) (*tokenResponse, error) {
+	var token tokenResponse
+	if err := a.post(ctx, tokenURL, form, &token); err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("login failed: no access token in response")
+	}
+	return &token, nil
+}
+
+func (a *Authenticator) post(ctx context.Context, endpoint string, form url.Values, out *tokenResponse) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach auth server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read auth response: %w", err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var failure tokenResponse
+		_ = json.Unmarshal(body, &failure)
+
+		if failure.Error == "invalid_grant" && form.Get("grant_type") == "refresh_token" {
+			return ErrSessionExpired
+		}
+		if failure.ErrorDescription != "" {
+			return fmt.Errorf("login failed: %s", failure.ErrorDescription)
+		}
+		return fmt.Errorf("login failed: status code %d", resp.StatusCode)
+	}
+
+	if out == nil || len(body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode auth response: %w", err)
+	}
+	return nil
+}
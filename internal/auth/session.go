@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/storage"
+)
+
+const sessionFileName = "session.json"
+
+// Session is the persisted part of a login. The password and access token
+// are never written to disk.
+type Session struct {
+	Username         string    `json:"username"`
+	ClientID         string    `json:"clientId"`
+	ClientSecret     string    `json:"clientSecret"`
+	RefreshToken     string    `json:"refreshToken"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
+
+// Expired reports whether the refresh token has run out, so the session
+// can no longer be used
+func (s *Session) Expired(now time.Time) bool {
+	return !s.RefreshExpiresAt.IsZero() && now.After(s.RefreshExpiresAt)
+}
+
+// Store reads and writes the session file. The file is only readable by
+// the current user.
+type Store struct {
+	path string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{path: filepath.Join(dir, sessionFileName)}
+}
+
+// Load returns the saved session, or nil if there is none
+func (s *Store) Load() (*Session, error) {
+	var session Session
+	ok, err := storage.ReadJSON(s.path, &session)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// Save atomically replaces the session file
+func (s *Store) Save(session *Session) error {
+	return storage.WriteJSON(s.path, session, 0600)
+}
+
+// Clear removes the session file
+func (s *Store) Clear() error {
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,399 @@
+// Package cache keeps HTTP responses on disk so covers, pages and API
+// listings are not downloaded again on every visit.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/storage"
+)
+
+const (
+	indexFileName = "index.json"
+	blobDirName   = "blobs"
+
+	// flushInterval is how often Run drops expired entries and writes a
+	// changed index to disk
+	flushInterval = 30 * time.Second
+)
+
+// DefaultMaxBytes is the cache size used when none is configured
+const DefaultMaxBytes = 512 << 20
+
+// Entry describes a cached response. Bodies are stored by the hash of their
+// content, so identical responses under different keys share one file.
+type Entry struct {
+	Hash         string    `json:"hash"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"contentType,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	StoredAt     time.Time `json:"storedAt"`
+	UsedAt       time.Time `json:"usedAt"`
+	// FreshUntil is when the entry has to be revalidated before use
+	FreshUntil time.Time `json:"freshUntil"`
+	// ExpiresAt is when the entry is dropped altogether
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Fresh reports whether the entry can be used without asking the server
+func (e Entry) Fresh(now time.Time) bool {
+	return now.Before(e.FreshUntil)
+}
+
+// Usage is how much of the cache is in use
+type Usage struct {
+	Entries  int
+	Bytes    int64
+	MaxBytes int64
+}
+
+// Cache is a size-limited, content-addressed store of response bodies.
+// Entries expire after their TTL and the least recently used ones are
+// evicted once the cache grows past its limit.
+type Cache struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	entries  map[string]Entry
+	// blobs counts the entries sharing each stored body
+	blobs map[string]int
+	size  int64
+	dirty bool
+}
+
+// Open loads the cache kept in dir, holding at most maxBytes of bodies
+func Open(dir string, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(filepath.Join(dir, blobDirName), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+
+	c := &Cache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		entries:  make(map[string]Entry),
+		blobs:    make(map[string]int),
+	}
+	if _, err := storage.ReadJSON(filepath.Join(dir, indexFileName), &c.entries); err != nil {
+		return nil, err
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]Entry)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		if _, err := os.Stat(c.blobPath(entry.Hash)); err != nil {
+			delete(c.entries, key)
+			c.dirty = true
+			continue
+		}
+		c.addBlobLocked(entry)
+	}
+	c.removeOrphansLocked()
+	c.expireLocked(time.Now())
+	c.evictLocked()
+
+	return c, nil
+}
+
+// Get returns a cached entry and its body. Entries past their expiry are
+// never returned, stale ones are and should be revalidated.
+func (c *Cache) Get(key string) (Entry, []byte, bool) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if !ok || !time.Now().Before(entry.ExpiresAt) {
+		return Entry{}, nil, false
+	}
+
+	body, err := os.ReadFile(c.blobPath(entry.Hash))
+	if err != nil {
+		log.Printf("Error reading cached %s: %v", key, err)
+		c.Delete(key)
+		return Entry{}, nil, false
+	}
+
+	c.mu.Lock()
+	if current, ok := c.entries[key]; ok && current.Hash == entry.Hash {
+		current.UsedAt = time.Now()
+		c.entries[key] = current
+		c.dirty = true
+	}
+	c.mu.Unlock()
+
+	return entry, body, true
+}
+
+// Put stores body under key. Hash, Size and the timestamps of entry other
+// than FreshUntil and ExpiresAt are filled in.
+func (c *Cache) Put(key string, entry Entry, body []byte) error {
+	sum := sha256.Sum256(body)
+	entry.Hash = hex.EncodeToString(sum[:])
+	entry.Size = int64(len(body))
+	entry.StoredAt = time.Now()
+	entry.UsedAt = entry.StoredAt
+
+	if entry.Size > c.maxBytes {
+		return nil
+	}
+
+	// Write outside the lock, but only move the body into place under it,
+	// so it cannot race with an entry sharing the body being removed
+	tmp, err := c.writeTemp(entry.Hash, body)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old, ok := c.entries[key]
+	if ok && old.Hash == entry.Hash {
+		// Same body as before, so only the details change
+		c.entries[key] = entry
+		c.dirty = true
+		return nil
+	}
+	if ok {
+		c.removeLocked(key, old)
+		c.dirty = true
+	}
+
+	if c.blobs[entry.Hash] == 0 {
+		if err := os.Rename(tmp, c.blobPath(entry.Hash)); err != nil {
+			return fmt.Errorf("failed to write cache: %w", err)
+		}
+	}
+	c.entries[key] = entry
+	c.addBlobLocked(entry)
+	c.dirty = true
+
+	c.evictLocked()
+	return nil
+}
+
+// Refresh extends how long an entry stays fresh after the server confirmed
+// it is unchanged
+func (c *Cache) Refresh(key string, freshUntil, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	entry.FreshUntil = freshUntil
+	entry.ExpiresAt = expiresAt
+	entry.UsedAt = time.Now()
+	c.entries[key] = entry
+	c.dirty = true
+}
+
+// Delete drops a cached entry
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[key]; ok {
+		c.removeLocked(key, entry)
+		c.dirty = true
+	}
+}
+
+// Usage returns the number of entries and bytes stored
+func (c *Cache) Usage() Usage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Usage{Entries: len(c.entries), Bytes: c.size, MaxBytes: c.maxBytes}
+}
+
+// Clear removes everything from the cache
+func (c *Cache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	blobDir := filepath.Join(c.dir, blobDirName)
+	if err := os.RemoveAll(blobDir); err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+	if err := os.MkdirAll(blobDir, 0700); err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+
+	c.entries = make(map[string]Entry)
+	c.blobs = make(map[string]int)
+	c.size = 0
+	c.dirty = true
+	return c.saveLocked()
+}
+
+// Run drops expired entries and writes the index to disk periodically
+// until ctx is cancelled
+func (c *Cache) Run(ctx context.Context) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			c.mu.Lock()
+			c.expireLocked(now)
+			c.mu.Unlock()
+
+			if err := c.Flush(); err != nil {
+				log.Println("Error saving cache index:", err)
+			}
+		}
+	}
+}
+
+// Flush writes the index to disk if it changed
+func (c *Cache) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+	return c.saveLocked()
+}
+
+// writeTemp writes a body next to where it is stored and returns the path
+// of the temporary file
+func (c *Cache) writeTemp(hash string, body []byte) (string, error) {
+	dir := filepath.Dir(c.blobPath(hash))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to write cache: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, hash+".*")
+	if err != nil {
+		return "", fmt.Errorf("failed to write cache: %w", err)
+	}
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write cache: %w", err)
+	}
+	return tmp.Name(), nil
+}
+
+// blobPath spreads bodies over subdirectories named after the first
+// characters of their hash
+func (c *Cache) blobPath(hash string) string {
+	return filepath.Join(c.dir, blobDirName, hash[:2], hash)
+}
+
+// addBlobLocked counts a reference to the body of entry; the caller holds the lock
+func (c *Cache) addBlobLocked(entry Entry) {
+	if c.blobs[entry.Hash] == 0 {
+		c.size += entry.Size
+	}
+	c.blobs[entry.Hash]++
+}
+
+// removeLocked drops an entry and its body once nothing else shares it;
+// the caller holds the lock
+func (c *Cache) removeLocked(key string, entry Entry) {
+	delete(c.entries, key)
+
+	c.blobs[entry.Hash]--
+	if c.blobs[entry.Hash] > 0 {
+		return
+	}
+	delete(c.blobs, entry.Hash)
+	c.size -= entry.Size
+	if err := os.Remove(c.blobPath(entry.Hash)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Println("Error removing cached body:", err)
+	}
+}
+
+// removeOrphansLocked deletes bodies no entry refers to, such as those
+// stored after the index was last written before a crash, and temporary
+// files left behind; the caller holds the lock
+func (c *Cache) removeOrphansLocked() {
+	blobDir := filepath.Join(c.dir, blobDirName)
+	err := filepath.WalkDir(blobDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if c.blobs[d.Name()] > 0 && path == c.blobPath(d.Name()) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			log.Println("Error removing orphaned cache body:", err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println("Error looking for orphaned cache bodies:", err)
+	}
+}
+
+// expireLocked drops entries past their expiry; the caller holds the lock
+func (c *Cache) expireLocked(now time.Time) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.ExpiresAt) {
+			c.removeLocked(key, entry)
+			c.dirty = true
+		}
+	}
+}
+
+// evictLocked drops the least recently used entries until the cache fits
+// its limit; the caller holds the lock
+func (c *Cache) evictLocked() {
+	if c.size <= c.maxBytes {
+		return
+	}
+
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.entries[keys[i]].UsedAt.Before(c.entries[keys[j]].UsedAt)
+	})
+
+	for _, key := range keys {
+		if c.size <= c.maxBytes {
+			break
+		}
+		c.removeLocked(key, c.entries[key])
+		c.dirty = true
+	}
+}
+
+// saveLocked writes the index to disk; the caller holds the lock
+func (c *Cache) saveLocked() error {
+	if err := storage.WriteJSON(filepath.Join(c.dir, indexFileName), c.entries, 0600); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTest(t *testing.T, dir string, maxBytes int64) *Cache {
+	t.Helper()
+	c, err := Open(dir, maxBytes)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return c
+}
+
+func put(t *testing.T, c *Cache, key, body string, ttl time.Duration) {
+	t.Helper()
+	now := time.Now()
+	entry := Entry{FreshUntil: now.Add(ttl), ExpiresAt: now.Add(ttl)}
+	if err := c.Put(key, entry, []byte(body)); err != nil {
+		t.Fatalf("Put(%q) error = %v", key, err)
+	}
+}
+
+func wantBody(t *testing.T, c *Cache, key, want string) {
+	t.Helper()
+	_, body, ok := c.Get(key)
+	if !ok {
+		t.Fatalf("Get(%q) found nothing, want %q", key, want)
+	}
+	if string(body) != want {
+		t.Fatalf("Get(%q) = %q, want %q", key, body, want)
+	}
+}
+
+func wantMissing(t *testing.T, c *Cache, key string) {
+	t.Helper()
+	if _, body, ok := c.Get(key); ok {
+		t.Fatalf("Get(%q) = %q, want nothing", key, body)
+	}
+}
+
+// blobCount counts the files kept for bodies
+func blobCount(t *testing.T, dir string) int {
+	t.Helper()
+	count := 0
+	err := filepath.Walk(filepath.Join(dir, blobDirName), func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			count++
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestPutSameBodyAgain(t *testing.T) {
+	dir := t.TempDir()
+	c := openTest(t, dir, 1<<20)
+
+	put(t, c, "cover", "jpeg bytes", time.Hour)
+	put(t, c, "cover", "jpeg bytes", 2*time.Hour)
+
+	wantBody(t, c, "cover", "jpeg bytes")
+	if got := blobCount(t, dir); got != 1 {
+		t.Errorf("%d bodies stored, want 1", got)
+	}
+	if usage := c.Usage(); usage.Entries != 1 || usage.Bytes != int64(len("jpeg bytes")) {
+		t.Errorf("Usage() = %+v", usage)
+	}
+}
+
+func TestPutNewBody(t *testing.T) {
+	dir := t.TempDir()
+	c := openTest(t, dir, 1<<20)
+
+	put(t, c, "listing", "old", time.Hour)
+	put(t, c, "listing", "new", time.Hour)
+
+	wantBody(t, c, "listing", "new")
+	if got := blobCount(t, dir); got != 1 {
+		t.Errorf("%d bodies stored, want 1", got)
+	}
+}
+
+func TestSharedBodies(t *testing.T) {
+	dir := t.TempDir()
+	c := openTest(t, dir, 1<<20)
+
+	put(t, c, "a", "same", time.Hour)
+	put(t, c, "b", "same", time.Hour)
+	if got := blobCount(t, dir); got != 1 {
+		t.Fatalf("%d bodies stored, want 1", got)
+	}
+	if got := c.Usage().Bytes; got != 4 {
+		t.Errorf("Usage().Bytes = %d, want 4", got)
+	}
+
+	c.Delete("a")
+	wantMissing(t, c, "a")
+	wantBody(t, c, "b", "same")
+
+	// Moving the last key to another body frees the shared one
+	put(t, c, "b", "other", time.Hour)
+	wantBody(t, c, "b", "other")
+	if got := blobCount(t, dir); got != 1 {
+		t.Errorf("%d bodies stored, want 1", got)
+	}
+}
+
+func TestEviction(t *testing.T) {
+	dir := t.TempDir()
+	c := openTest(t, dir, 10)
+
+	put(t, c, "a", "aaaa", time.Hour)
+	time.Sleep(2 * time.Millisecond)
+	put(t, c, "b", "bbbb", time.Hour)
+	time.Sleep(2 * time.Millisecond)
+	wantBody(t, c, "a", "aaaa")
+	time.Sleep(2 * time.Millisecond)
+
+	// b is the least recently used, so it makes room for c
+	put(t, c, "c", "cccc", time.Hour)
+	wantMissing(t, c, "b")
+	wantBody(t, c, "a", "aaaa")
+	wantBody(t, c, "c", "cccc")
+	if got := c.Usage().Bytes; got != 8 {
+		t.Errorf("Usage().Bytes = %d, want 8", got)
+	}
+
+	// Bodies larger than the whole cache are not kept
+	put(t, c, "big", "0123456789abc", time.Hour)
+	wantMissing(t, c, "big")
+}
+
+func TestExpiry(t *testing.T) {
+	dir := t.TempDir()
+	c := openTest(t, dir, 1<<20)
+
+	put(t, c, "gone", "old", -time.Second)
+	put(t, c, "kept", "new", time.Hour)
+	wantMissing(t, c, "gone")
+	wantBody(t, c, "kept", "new")
+
+	c.mu.Lock()
+	c.expireLocked(time.Now())
+	c.mu.Unlock()
+	if usage := c.Usage(); usage.Entries != 1 || usage.Bytes != 3 {
+		t.Errorf("Usage() = %+v after expiry", usage)
+	}
+	if got := blobCount(t, dir); got != 1 {
+		t.Errorf("%d bodies stored, want 1", got)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	c := openTest(t, dir, 1<<20)
+
+	put(t, c, "kept", "body", time.Hour)
+	put(t, c, "expired", "stale", -time.Second)
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stored after the last flush, as if the app crashed before the next
+	put(t, c, "unsaved", "lost", time.Hour)
+	if err := os.WriteFile(filepath.Join(dir, blobDirName, "leftover.tmp"), []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c = openTest(t, dir, 1<<20)
+	wantBody(t, c, "kept", "body")
+	wantMissing(t, c, "expired")
+	wantMissing(t, c, "unsaved")
+	if got := blobCount(t, dir); got != 1 {
+		t.Errorf("%d bodies stored after reopening, want 1", got)
+	}
+}
@@ -0,0 +1,210 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// imageTTL is how long covers and pages are kept. Their URLs change
+	// whenever the image does, so they never need revalidating.
+	imageTTL = 30 * 24 * time.Hour
+
+	// listingFreshness is how long an API listing is used without asking
+	// MangaDex whether it changed
+	listingFreshness = 5 * time.Minute
+
+	// listingTTL is how long an API listing is kept for revalidation
+	listingTTL = 7 * 24 * time.Hour
+
+	// hitHeader marks responses served from the cache
+	hitHeader = "X-Disk-Cache"
+)
+
+// policy is how a request is cached
+type policy struct {
+	key        string
+	freshness  time.Duration
+	ttl        time.Duration
+	revalidate bool
+}
+
+// Transport is an http.RoundTripper serving covers, chapter pages and the
+// /manga and /chapter listings from a Cache. Listings are revalidated with
+// ETag and Last-Modified once they go stale, and served stale when MangaDex
+// can't be reached.
+type Transport struct {
+	cache *Cache
+	next  http.RoundTripper
+
+	mu      sync.Mutex
+	onStale func(err error)
+}
+
+// NewTransport returns a Transport storing responses in cache and sending
+// requests through next, or http.DefaultTransport if next is nil
+func NewTransport(cache *Cache, next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{cache: cache, next: next}
+}
+
+// SetStaleFunc sets a function called with the network error whenever a
+// stale response is served in place of a failed request, since callers do
+// not get to see that error
+func (t *Transport) SetStaleFunc(fn func(err error)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.onStale = fn
+}
+
+// IsHit reports whether the body of a response came from the cache
+func IsHit(resp *http.Response) bool {
+	return resp.Header.Get(hitHeader) != ""
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p, ok := policyFor(req)
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+
+	entry, body, cached := t.cache.Get(p.key)
+	if cached && entry.Fresh(time.Now()) {
+		return cachedResponse(req, entry, body, "hit"), nil
+	}
+
+	outgoing := req
+	if cached && p.revalidate {
+		outgoing = req.Clone(req.Context())
+		if entry.ETag != "" {
+			outgoing.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			outgoing.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(outgoing)
+	if err != nil {
+		if cached && req.Context().Err() == nil {
+			log.Printf("Serving stale %s: %v", p.key, err)
+			t.mu.Lock()
+			onStale := t.onStale
+			t.mu.Unlock()
+			if onStale != nil {
+				onStale(err)
+			}
+			return cachedResponse(req, entry, body, "stale"), nil
+		}
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached:
+		resp.Body.Close()
+		now := time.Now()
+		t.cache.Refresh(p.key, now.Add(p.freshness), now.Add(p.ttl))
+		return cachedResponse(req, entry, body, "revalidated"), nil
+	case resp.StatusCode != http.StatusOK:
+		return resp, nil
+	}
+
+	return t.store(p, resp)
+}
+
+// store reads a successful response into the cache and hands back a copy
+func (t *Transport) store(p policy, resp *http.Response) (*http.Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	now := time.Now()
+	entry := Entry{
+		ContentType:  resp.Header.Get("Content-Type"),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		FreshUntil:   now.Add(p.freshness),
+		ExpiresAt:    now.Add(p.ttl),
+	}
+	if noStore(resp.Header) {
+		return resp, nil
+	}
+	if err := t.cache.Put(p.key, entry, body); err != nil {
+		log.Println("Error caching response:", err)
+	}
+	return resp, nil
+}
+
+// policyFor decides whether and how a request is cached
+func policyFor(req *http.Request) (policy, bool) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return policy{}, false
+	}
+
+	path := req.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/covers/"):
+		return policy{key: "cover:" + path, freshness: imageTTL, ttl: imageTTL}, true
+	case path == "/manga" || path == "/chapter":
+		return policy{
+			key:        "api:" + req.URL.Host + path + "?" + req.URL.RawQuery,
+			freshness:  listingFreshness,
+			ttl:        listingTTL,
+			revalidate: true,
+		}, true
+	}
+
+	// MangaDex@Home URLs differ between nodes but the path from /data/ or
+	// /data-saver/ on identifies the page
+	for _, marker := range []string{"/data/", "/data-saver/"} {
+		if i := strings.Index(path, marker); i >= 0 {
+			return policy{key: "page:" + path[i:], freshness: imageTTL, ttl: imageTTL}, true
+		}
+	}
+	return policy{}, false
+}
+
+// noStore reports whether the server asked for the response not to be kept
+func noStore(header http.Header) bool {
+	return strings.Contains(strings.ToLower(header.Get("Cache-Control")), "no-store")
+}
+
+func cachedResponse(req *http.Request, entry Entry, body []byte, status string) *http.Response {
+	header := make(http.Header)
+	if entry.ContentType != "" {
+		header.Set("Content-Type", entry.ContentType)
+	}
+	if entry.ETag != "" {
+		header.Set("ETag", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		header.Set("Last-Modified", entry.LastModified)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	header.Set(hitHeader, status)
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", http.StatusOK, http.StatusText(http.StatusOK)),
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -7,70 +7,155 @@ import (
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/graphics"
 )
 
-// ImageView is a component that displays images in the terminal
+// ImageView is a component that displays images in the terminal. Images are
+// drawn by its ImageLayer when the terminal supports a graphics protocol,
+// and as half-block cells otherwise.
 type ImageView struct {
 	*tview.Box
 	image image.Image
+	layer *ImageLayer
+	cells *halfBlocks
 }
 
-// NewImageView creates and returns a new image view
-func NewImageView() *ImageView {
+// NewImageView creates and returns a new image view drawing through layer,
+// which may be nil to always draw half-blocks
+func NewImageView(layer *ImageLayer) *ImageView {
 	return &ImageView{
-		Box: tview.NewBox(),
+		Box:   tview.NewBox(),
+		layer: layer,
 	}
 }
 
 // SetImage sets the image to be displayed
 func (i *ImageView) SetImage(img image.Image) *ImageView {
+	if img != i.image {
+		i.cells = nil
+	}
 	i.image = img
 	return i
 }
 
 // Draw draws this primitive onto the screen
 func (i *ImageView) Draw(screen tcell.Screen) {
+	i.Box.DrawForSubclass(screen, i)
+
 	if i.image == nil {
 		return
 	}
 
-	i.Box.Draw(screen)
-
 	x, y, width, height := i.GetInnerRect()
-	imgWidth := i.image.Bounds().Dx()
-	imgHeight := i.image.Bounds().Dy()
+	if width <= 0 || height <= 0 {
+		return
+	}
+	if i.layer.place(screen, i, i.image, x, y, width, height) {
+		return
+	}
 
-	// Calculate scaling factors
-	scaleX := float64(imgWidth) / float64(width)
-	scaleY := float64(imgHeight) / float64(height*2) // Each terminal cell is roughly twice as tall as wide
+	i.drawHalfBlocks(screen, x, y, width, height)
+}
 
-	for cy := 0; cy < height; cy++ {
-		for cx := 0; cx < width; cx++ {
-			// Sample the image (using the top and bottom half of where this cell represents)
-			topY := int(float64(cy*2) * scaleY)
-			bottomY := int(float64(cy*2+1) * scaleY)
-			imageX := int(float64(cx) * scaleX)
+// halfBlocks is an image rendered as half-block cells. Rendering scales
+// the whole image, so the cells are kept for as long as the image, the
+// rectangle and the colours stay the same.
+type halfBlocks struct {
+	img    image.Image
+	rect   image.Rectangle
+	cell   graphics.CellSize
+	colors int
+	dither bool
 
-			if topY >= imgHeight || bottomY >= imgHeight || imageX >= imgWidth {
-				continue
-			}
+	// bounds are the cells the image covers and cells their colours, top
+	// then bottom half
+	bounds image.Rectangle
+	cells  []tcell.Color
+}
 
-			topColor := i.image.At(imageX, topY)
-			bottomColor := i.image.At(imageX, bottomY)
+// drawHalfBlocks draws the image as cells of two pixels each, fitted into
+// the rectangle with its aspect ratio kept
+func (i *ImageView) drawHalfBlocks(screen tcell.Screen, x, y, width, height int) {
+	rect := image.Rect(x, y, x+width, y+height)
+	cell := i.layer.cellSize(screen)
+	colors := i.layer.colors(screen)
+	dither := i.layer.Dither()
 
-			// Convert to tcell colors
-			r1, g1, b1, _ := topColor.RGBA()
-			r2, g2, b2, _ := bottomColor.RGBA()
+	if c := i.cells; c == nil || c.img != i.image || c.rect != rect || c.cell != cell ||
+		c.colors != colors || c.dither != dither {
+		i.cells = renderHalfBlocks(i.image, rect, cell, colors, dither)
+	}
 
-			topTcellColor := tcell.NewRGBColor(int32(r1>>8), int32(g1>>8), int32(b1>>8))
-			bottomTcellColor := tcell.NewRGBColor(int32(r2>>8), int32(g2>>8), int32(b2>>8))
+	c := i.cells
+	cols := c.bounds.Dx()
+	for cy := 0; cy < c.bounds.Dy(); cy++ {
+		for cx := 0; cx < cols; cx++ {
+			top := c.cells[(cy*cols+cx)*2]
+			bottom := c.cells[(cy*cols+cx)*2+1]
 
-			// Use Unicode block characters for improved resolution
-			// '▀' (U+2580) is a half block where the top half is the foreground color
-			// and the bottom half is the background color
-			screen.SetContent(x+cx, y+cy, '▀', nil, tcell.StyleDefault.
-				Background(bottomTcellColor).
-				Foreground(topTcellColor))
+			// '▀' (U+2580) is a half block where the top half is the foreground
+			// color and the bottom half is the background color
+			screen.SetContent(c.bounds.Min.X+cx, c.bounds.Min.Y+cy, '▀', nil, tcell.StyleDefault.
+				Background(bottom).
+				Foreground(top))
+		}
+	}
+}
+
+// renderHalfBlocks scales img into rect, averaging the pixels that fall into
+// each half cell, and picks the colours of the cells. Terminals without true
+// colour get xterm colours, dithered if asked to.
+func renderHalfBlocks(img image.Image, rect image.Rectangle, cell graphics.CellSize, colors int, dither bool) *halfBlocks {
+	h := &halfBlocks{
+		img:    img,
+		rect:   rect,
+		cell:   cell,
+		colors: colors,
+		dither: dither,
+	}
+
+	// A half cell is cell.Width by cell.Height/2 pixels, so fit in pixels
+	// and count how many of those it takes
+	fit := graphics.Fit(img.Bounds(), rect.Dx()*cell.Width, rect.Dy()*cell.Height)
+	if fit.X == 0 || fit.Y == 0 {
+		return h
+	}
+	cols := min(rect.Dx(), max(1, (fit.X+cell.Width/2)/cell.Width))
+	halves := min(rect.Dy()*2, max(1, (fit.Y*2+cell.Height/2)/cell.Height))
+	rows := (halves + 1) / 2
+
+	left := rect.Min.X + (rect.Dx()-cols)/2
+	top := rect.Min.Y + (rect.Dy()-rows)/2
+	h.bounds = image.Rect(left, top, left+cols, top+rows)
+
+	scaled := graphics.Resize(img, cols, halves)
+	var indexes []int
+	if dither && colors == 256 {
+		indexes = graphics.DitherXterm256(scaled)
+	}
+
+	// An odd number of halves leaves the bottom of the last row empty
+	h.cells = make([]tcell.Color, cols*rows*2)
+	for i := range h.cells {
+		h.cells[i] = tcell.ColorDefault
+	}
+	for py := 0; py < halves; py++ {
+		for px := 0; px < cols; px++ {
+			cy, half := py/2, py%2
+			c := &h.cells[(cy*cols+px)*2+half]
+
+			if indexes != nil {
+				if index := indexes[py*cols+px]; index >= 0 {
+					*c = tcell.PaletteColor(index)
+				}
+				continue
+			}
+			pixel := scaled.NRGBAAt(px, py)
+			if pixel.A >= 0x80 {
+				*c = tcell.NewRGBColor(int32(pixel.R), int32(pixel.G), int32(pixel.B))
+			}
 		}
 	}
+	return h
 }
@@ -0,0 +1,312 @@
+package components
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"log"
+
+	"github.com/gdamore/tcell/v2"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/graphics"
+)
+
+// placement is an image shown with a graphics protocol, in cells
+type placement struct {
+	img  image.Image
+	rect image.Rectangle
+	cell graphics.CellSize
+	id   uint32
+}
+
+func (p placement) same(other placement) bool {
+	return p.img == other.img && p.rect == other.rect && p.cell == other.cell
+}
+
+// encoding is an image scaled and encoded for a placement. Where it goes is
+// written separately, so a placement that only moved reuses it.
+type encoding struct {
+	img  image.Image
+	size image.Point
+	cell graphics.CellSize
+	id   uint32
+
+	// scaled is the image fitted into the pixels of the placement, and data
+	// the encoded image once done is set
+	scaled *image.NRGBA
+	data   []byte
+	done   bool
+}
+
+func (e *encoding) fits(p placement) bool {
+	return e.img == p.img && e.size == p.rect.Size() && e.cell == p.cell
+}
+
+// ImageLayer draws the images of ImageViews with a terminal graphics
+// protocol. tcell knows nothing about those pixels, so the layer writes
+// them straight to the terminal once tcell has drawn a frame, locks the
+// cells underneath so tcell leaves them alone, and removes images whose
+// view was not drawn in the latest frame.
+//
+// Scaling and encoding a page takes long enough to be felt, so it runs in
+// the background and the image appears once a queued update brings the
+// result back.
+//
+// BeforeDraw and AfterDraw must be installed as the application's draw
+// hooks. Everything but encoding runs on the event loop, so no locking is
+// needed.
+type ImageLayer struct {
+	encoder   graphics.Encoder
+	caps      graphics.Capabilities
+	dither    bool
+	queue     func(func())
+	shown     map[*ImageView]placement
+	frame     map[*ImageView]placement
+	encodings map[*ImageView]*encoding
+	size      image.Point
+	nextID    uint32
+}
+
+// NewImageLayer returns a layer drawing with protocol for a terminal with
+// caps. With half-blocks, or a protocol without an encoder, views draw
+// themselves as cells.
+func NewImageLayer(protocol graphics.Protocol, caps graphics.Capabilities) *ImageLayer {
+	encoder, _ := graphics.NewEncoder(protocol)
+	return &ImageLayer{
+		encoder:   encoder,
+		caps:      caps,
+		shown:     make(map[*ImageView]placement),
+		frame:     make(map[*ImageView]placement),
+		encodings: make(map[*ImageView]*encoding),
+	}
+}
+
+// SetQueueFunc sets the function that runs an update on the event loop and
+// redraws, such as Application.QueueUpdateDraw. Without one images are
+// encoded while drawing.
+func (l *ImageLayer) SetQueueFunc(queue func(func())) {
+	l.queue = queue
+}
+
+// Protocol returns the protocol images are drawn with
+func (l *ImageLayer) Protocol() graphics.Protocol {
+	if l == nil || l.encoder == nil {
+		return graphics.HalfBlocks
+	}
+	return l.encoder.Protocol()
+}
+
+// Capabilities returns what the terminal was found to support
+func (l *ImageLayer) Capabilities() graphics.Capabilities {
+	if l == nil {
+		return graphics.Capabilities{Protocol: graphics.HalfBlocks, CellSize: graphics.DefaultCellSize}
+	}
+	return l.caps
+}
+
+// colors returns how many colours cells can take. The probe can know of true
+// colour where tcell's terminfo does not, and tcell maps colours it cannot
+// show to the nearest it can.
+func (l *ImageLayer) colors(screen tcell.Screen) int {
+	if l != nil && l.caps.TrueColor {
+		return max(screen.Colors(), 1<<24)
+	}
+	return screen.Colors()
+}
+
+// Dither reports whether half-block images are dithered on 256 colour
+// terminals
+func (l *ImageLayer) Dither() bool {
+	return l != nil && l.dither
+}
+
+// SetDither turns dithering of half-block images on or off
+func (l *ImageLayer) SetDither(dither bool) {
+	l.dither = dither
+}
+
+// BeforeDraw starts a frame
+func (l *ImageLayer) BeforeDraw(screen tcell.Screen) bool {
+	l.frame = make(map[*ImageView]placement)
+	return false
+}
+
+// place asks for an image to be drawn fitted into a rectangle of cells. It
+// returns false if the view has to draw the image itself.
+func (l *ImageLayer) place(screen tcell.Screen, view *ImageView, img image.Image, x, y, width, height int) bool {
+	if l == nil || l.encoder == nil {
+		return false
+	}
+	if _, ok := screen.Tty(); !ok {
+		return false
+	}
+	cell := l.cellSize(screen)
+
+	// Fit the image into the pixels of the rectangle and center it
+	fit := graphics.Fit(img.Bounds(), width*cell.Width, height*cell.Height)
+	cols := min(width, (fit.X+cell.Width-1)/cell.Width)
+	rows := min(height, (fit.Y+cell.Height-1)/cell.Height)
+	if cols <= 0 || rows <= 0 {
+		return true
+	}
+	left := x + (width-cols)/2
+	top := y + (height-rows)/2
+
+	l.frame[view] = placement{
+		img:  img,
+		rect: image.Rect(left, top, left+cols, top+rows),
+		cell: cell,
+	}
+	return true
+}
+
+// cellSize returns the size of a cell in pixels. It may change with the
+// font, so the terminal is asked again before falling back on the probed
+// size.
+func (l *ImageLayer) cellSize(screen tcell.Screen) graphics.CellSize {
+	cell := graphics.DefaultCellSize
+	if l != nil && l.caps.CellSize.Width > 0 && l.caps.CellSize.Height > 0 {
+		cell = l.caps.CellSize
+	}
+	if tty, ok := screen.Tty(); ok {
+		if size, err := tty.WindowSize(); err == nil {
+			if w, h := size.CellDimensions(); w > 0 && h > 0 {
+				cell = graphics.CellSize{Width: w, Height: h}
+			}
+		}
+	}
+	return cell
+}
+
+// AfterDraw shows the frame and then draws, moves and removes images
+func (l *ImageLayer) AfterDraw(screen tcell.Screen) {
+	if l.encoder == nil {
+		return
+	}
+	tty, ok := screen.Tty()
+	if !ok {
+		return
+	}
+
+	// A resize clears the screen, so every image is drawn again
+	width, height := screen.Size()
+	resized := l.size != image.Pt(width, height)
+	l.size = image.Pt(width, height)
+
+	// Give cells of images that moved or went away back to tcell
+	var deleted []uint32
+	for view, old := range l.shown {
+		if current, ok := l.frame[view]; ok && !resized && current.same(old) {
+			continue
+		}
+		lockRect(screen, old.rect, false)
+		deleted = append(deleted, old.id)
+	}
+
+	screen.Show()
+
+	var out bytes.Buffer
+	for _, id := range deleted {
+		var data bytes.Buffer
+		if err := l.encoder.Delete(&data, id); err != nil {
+			log.Println("Error removing image:", err)
+			continue
+		}
+		if data.Len() > 0 {
+			out.Write(l.caps.Passthrough(data.Bytes()))
+		}
+	}
+	shown := make(map[*ImageView]placement, len(l.frame))
+	for view, p := range l.frame {
+		if old, ok := l.shown[view]; ok && !resized && p.same(old) {
+			shown[view] = old
+			continue
+		}
+
+		// Images still being encoded leave their cells to tcell for now
+		enc := l.encodings[view]
+		if enc == nil || !enc.fits(p) {
+			enc = l.startEncoding(view, p, enc)
+		}
+		if !enc.done || enc.data == nil {
+			continue
+		}
+
+		p.id = enc.id
+		shown[view] = p
+		writeAt(&out, p.rect.Min, l.caps.Passthrough(enc.data))
+		lockRect(screen, p.rect, true)
+	}
+	l.shown = shown
+	for view := range l.encodings {
+		if _, ok := l.frame[view]; !ok {
+			delete(l.encodings, view)
+		}
+	}
+
+	if out.Len() > 0 {
+		if _, err := tty.Write(out.Bytes()); err != nil {
+			log.Println("Error drawing image:", err)
+		}
+	}
+}
+
+// startEncoding encodes an image for a placement in the background, reusing
+// the scaled image of the previous encoding when the size in pixels did
+// not change
+func (l *ImageLayer) startEncoding(view *ImageView, p placement, prev *encoding) *encoding {
+	l.nextID++
+	enc := &encoding{
+		img:  p.img,
+		size: p.rect.Size(),
+		cell: p.cell,
+		id:   l.nextID,
+	}
+	cols, rows := enc.size.X, enc.size.Y
+	fit := graphics.Fit(p.img.Bounds(), cols*p.cell.Width, rows*p.cell.Height)
+	if prev != nil && prev.img == p.img && prev.scaled != nil && prev.scaled.Bounds().Size() == fit {
+		enc.scaled = prev.scaled
+	}
+	l.encodings[view] = enc
+
+	run := func() (*image.NRGBA, []byte) {
+		scaled := enc.scaled
+		if scaled == nil {
+			scaled = graphics.Resize(p.img, fit.X, fit.Y)
+		}
+		var data bytes.Buffer
+		if err := l.encoder.Encode(&data, scaled, enc.id, cols, rows); err != nil {
+			log.Println("Error encoding image:", err)
+			return scaled, nil
+		}
+		return scaled, data.Bytes()
+	}
+
+	if l.queue == nil {
+		enc.scaled, enc.data = run()
+		enc.done = true
+		return enc
+	}
+	go func() {
+		scaled, data := run()
+		l.queue(func() {
+			enc.scaled, enc.data = scaled, data
+			enc.done = true
+		})
+	}()
+	return enc
+}
+
+// writeAt writes encoded image data with its top left at a cell, leaving
+// the cursor where tcell expects it
+func writeAt(out *bytes.Buffer, at image.Point, data []byte) {
+	// Save the cursor, move to the top left cell, draw and restore
+	out.WriteString("\x1b7")
+	fmt.Fprintf(out, "\x1b[%d;%dH", at.Y+1, at.X+1)
+	out.Write(data)
+	out.WriteString("\x1b8")
+}
+
+func lockRect(screen tcell.Screen, rect image.Rectangle, lock bool) {
+	screen.LockRegion(rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy(), lock)
+}
@@ -0,0 +1,85 @@
+package components
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+var spinnerFrames = []rune{'⠋', '⠙', '⠹', '⠸', '⠼', '⠴', '⠦', '⠧', '⠇', '⠏'}
+
+const spinnerInterval = 100 * time.Millisecond
+
+// Spinner is a placeholder that animates while data is loading
+type Spinner struct {
+	*tview.Box
+	label  string
+	status func() string
+	start  time.Time
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// NewSpinner creates and returns a new spinner showing the given label
+func NewSpinner(label string) *Spinner {
+	return &Spinner{
+		Box:   tview.NewBox(),
+		label: label,
+		start: time.Now(),
+		stop:  make(chan struct{}),
+	}
+}
+
+// SetStatusFunc sets a function queried on every frame. When it returns a
+// non-empty string, that text is shown below the label.
+func (s *Spinner) SetStatusFunc(status func() string) *Spinner {
+	s.status = status
+	return s
+}
+
+// Start animates the spinner until Stop is called. queueDraw is used to
+// request a redraw from the application event loop.
+func (s *Spinner) Start(queueDraw func(func())) *Spinner {
+	go func() {
+		ticker := time.NewTicker(spinnerInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				queueDraw(func() {})
+			}
+		}
+	}()
+	return s
+}
+
+// Stop ends the animation. It is safe to call more than once.
+func (s *Spinner) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+}
+
+// Draw draws this primitive onto the screen
+func (s *Spinner) Draw(screen tcell.Screen) {
+	s.Box.DrawForSubclass(screen, s)
+
+	x, y, width, height := s.GetInnerRect()
+	if width <= 0 || height <= 0 {
+		return
+	}
+
+	frame := int(time.Since(s.start)/spinnerInterval) % len(spinnerFrames)
+	text := string(spinnerFrames[frame]) + " " + s.label
+	tview.Print(screen, text, x, y+height/2, width, tview.AlignCenter, tcell.ColorYellow)
+
+	if s.status != nil && height > 1 {
+		if status := s.status(); status != "" {
+			tview.Print(screen, status, x, y+height/2+1, width, tview.AlignCenter, tcell.ColorOrange)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const appName = "mangadex-tui"
+
+// ConfigDir returns the per-user configuration directory, creating it if needed
+func ConfigDir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return ensureDir(filepath.Join(base, appName))
+}
+
+// DataDir returns the per-user data directory, creating it if needed.
+// It follows XDG_DATA_HOME and falls back to ~/.local/share.
+func DataDir() (string, error) {
+	base := os.Getenv("XDG_DATA_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to locate data directory: %w", err)
+		}
+		base = filepath.Join(home, ".local", "share")
+	}
+	return ensureDir(filepath.Join(base, appName))
+}
+
+// CacheDir returns the per-user cache directory, creating it if needed
+func CacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+	return ensureDir(filepath.Join(base, appName))
+}
+
+func ensureDir(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	return dir, nil
+}
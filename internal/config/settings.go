@@ -0,0 +1,77 @@
+package config
+
+import (
+	"path/filepath"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/storage"
+)
+
+const settingsFileName = "settings.json"
+
+// DefaultDownloadTemplate lays chapters out in one folder per manga
+const DefaultDownloadTemplate = "{manga}/{volume} Ch.{chapter} [{group}].cbz"
+
+// DefaultDownloadWorkers is how many download jobs run at once by default
+const DefaultDownloadWorkers = 2
+
+// DefaultCacheSizeMB is the default limit of the disk cache in megabytes
+const DefaultCacheSizeMB = 512
+
+// Settings are the user preferences kept in the config directory. Missing
+// fields fall back to their defaults.
+type Settings struct {
+	path string
+
+	// DownloadDir is where downloaded chapters are saved
+	DownloadDir string `json:"downloadDir"`
+	// DownloadTemplate is the path of a chapter archive inside DownloadDir
+	DownloadTemplate string `json:"downloadTemplate"`
+	// DownloadWorkers is how many download jobs run at once
+	DownloadWorkers int `json:"downloadWorkers"`
+	// ImageProtocol is how images are drawn: auto, halfblocks, kitty, sixel
+	// or iterm
+	ImageProtocol string `json:"imageProtocol"`
+	// Dither smooths half-block images on 256 colour terminals
+	Dither bool `json:"dither"`
+	// DataSaver reads chapters as compressed images by default
+	DataSaver bool `json:"dataSaver"`
+	// CacheSizeMB limits the disk cache of covers, pages and API listings
+	CacheSizeMB int `json:"cacheSizeMB"`
+}
+
+// LoadSettings reads the settings file, filling in defaults
+func LoadSettings() (*Settings, error) {
+	configDir, err := ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Settings{path: filepath.Join(configDir, settingsFileName)}
+	if _, err := storage.ReadJSON(s.path, s); err != nil {
+		return nil, err
+	}
+
+	if s.DownloadDir == "" {
+		dataDir, err := DataDir()
+		if err != nil {
+			return nil, err
+		}
+		s.DownloadDir = filepath.Join(dataDir, "downloads")
+	}
+	if s.DownloadTemplate == "" {
+		s.DownloadTemplate = DefaultDownloadTemplate
+	}
+	if s.DownloadWorkers < 1 {
+		s.DownloadWorkers = DefaultDownloadWorkers
+	}
+	if s.CacheSizeMB < 1 {
+		s.CacheSizeMB = DefaultCacheSizeMB
+	}
+
+	return s, nil
+}
+
+// Save writes the settings file
+func (s *Settings) Save() error {
+	return storage.WriteJSON(s.path, s, 0600)
+}
@@ -0,0 +1,120 @@
+package download
+
+import (
+	"archive/zip"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// imageExts are the archive entries read as pages
+var imageExts = map[string]bool{".jpg": true, ".jpeg": true, ".png": true}
+
+// Archive reads the pages of a CBZ archive in file name order, decoding
+// each only when it is asked for. Pages can be read concurrently.
+type Archive struct {
+	path  string
+	count int
+
+	mu     sync.RWMutex
+	reader *zip.ReadCloser
+	files  []*zip.File
+}
+
+// OpenArchive opens a CBZ archive and lists its pages
+func OpenArchive(archivePath string) (*Archive, error) {
+	a := &Archive{path: archivePath}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	a.count = len(a.files)
+	return a, nil
+}
+
+// Count returns the number of pages
+func (a *Archive) Count() int {
+	return a.count
+}
+
+// Page decodes page i. A closed archive is opened again.
+func (a *Archive) Page(i int) (image.Image, error) {
+	if i < 0 || i >= a.count {
+		return nil, fmt.Errorf("page %d out of range", i+1)
+	}
+
+	a.mu.RLock()
+	for a.reader == nil {
+		a.mu.RUnlock()
+		a.mu.Lock()
+		if a.reader == nil {
+			if err := a.open(); err != nil {
+				a.mu.Unlock()
+				return nil, err
+			}
+		}
+		a.mu.Unlock()
+		a.mu.RLock()
+	}
+	defer a.mu.RUnlock()
+
+	if len(a.files) != a.count {
+		return nil, fmt.Errorf("archive %s changed from %d to %d pages", a.path, a.count, len(a.files))
+	}
+	file := a.files[i]
+	img, err := decodeArchiveImage(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read page %s: %w", file.Name, err)
+	}
+	return img, nil
+}
+
+// Close releases the archive file until a page is read again
+func (a *Archive) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.reader == nil {
+		return nil
+	}
+	err := a.reader.Close()
+	a.reader, a.files = nil, nil
+	return err
+}
+
+// open opens the archive file and lists its pages; the caller holds the
+// lock or has the archive to itself
+func (a *Archive) open() error {
+	reader, err := zip.OpenReader(a.path)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	var files []*zip.File
+	for _, file := range reader.File {
+		if imageExts[strings.ToLower(path.Ext(file.Name))] {
+			files = append(files, file)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+
+	a.reader, a.files = reader, files
+	return nil
+}
+
+func decodeArchiveImage(file *zip.File) (image.Image, error) {
+	r, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	img, _, err := image.Decode(r)
+	return img, err
+}
@@ -0,0 +1,46 @@
+package download
+
+import (
+	"context"
+	"testing"
+)
+
+func TestArchive(t *testing.T) {
+	server := newAtHomeServer(t)
+	d := openDownloader(t, server.client(), t.TempDir())
+	manga, chapter := testManga("m1"), testChapter("c1", "1")
+	if _, err := d.Download(context.Background(), manga, chapter, nil); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+
+	archive, err := d.Pages(manga, chapter)
+	if err != nil {
+		t.Fatalf("Pages() error = %v", err)
+	}
+	defer archive.Close()
+	if archive.Count() != testPages {
+		t.Fatalf("Count() = %d, want %d", archive.Count(), testPages)
+	}
+
+	img, err := archive.Page(testPages - 1)
+	if err != nil {
+		t.Fatalf("Page() error = %v", err)
+	}
+	if size := img.Bounds().Size(); size.X != 2 || size.Y != 3 {
+		t.Errorf("Page() is %v, want 2x3", size)
+	}
+
+	// A closed archive is opened again for the next page
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := archive.Page(0); err != nil {
+		t.Errorf("Page() after Close() error = %v", err)
+	}
+
+	for _, i := range []int{-1, testPages} {
+		if _, err := archive.Page(i); err == nil {
+			t.Errorf("Page(%d) error = nil, want out of range", i)
+		}
+	}
+}
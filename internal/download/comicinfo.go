@@ -0,0 +1,96 @@
+package download
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+)
+
+const comicInfoFileName = "ComicInfo.xml"
+
+// ComicInfo is the metadata file read by comic readers such as Komga,
+// Kavita and Tachiyomi, following the Anansi ComicInfo 2.0 schema
+type ComicInfo struct {
+	XMLName         xml.Name `xml:"ComicInfo"`
+	Series          string   `xml:"Series,omitempty"`
+	Number          string   `xml:"Number,omitempty"`
+	Volume          string   `xml:"Volume,omitempty"`
+	Title           string   `xml:"Title,omitempty"`
+	Summary         string   `xml:"Summary,omitempty"`
+	Year            int      `xml:"Year,omitempty"`
+	Writer          string   `xml:"Writer,omitempty"`
+	Penciller       string   `xml:"Penciller,omitempty"`
+	Translator      string   `xml:"Translator,omitempty"`
+	Genre           string   `xml:"Genre,omitempty"`
+	Tags            string   `xml:"Tags,omitempty"`
+	Web             string   `xml:"Web,omitempty"`
+	PageCount       int      `xml:"PageCount,omitempty"`
+	LanguageISO     string   `xml:"LanguageISO,omitempty"`
+	ScanInformation string   `xml:"ScanInformation,omitempty"`
+	Manga           string   `xml:"Manga,omitempty"`
+}
+
+// NewComicInfo fills in ComicInfo from MangaDex metadata
+func NewComicInfo(manga models.Manga, chapter models.Chapter, pageCount int) ComicInfo {
+	var writers, artists, genres, tags []string
+	for _, rel := range manga.Relationships {
+		// Relationships without the expand parameter carry no name
+		if rel.Attributes.Name == "" {
+			continue
+		}
+		switch rel.Type {
+		case "author":
+			writers = append(writers, rel.Attributes.Name)
+		case "artist":
+			artists = append(artists, rel.Attributes.Name)
+		}
+	}
+	for _, tag := range manga.Attributes.Tags {
+		name := tag.Attributes.Name["en"]
+		if name == "" {
+			continue
+		}
+		if tag.Attributes.Group == "genre" {
+			genres = append(genres, name)
+		} else {
+			tags = append(tags, name)
+		}
+	}
+	groups := strings.Join(GroupNames(chapter), ", ")
+
+	// Japanese manga read right to left, manhwa and manhua left to right
+	readingDirection := "Yes"
+	if manga.Attributes.OriginalLanguage == "ja" {
+		readingDirection = "YesAndRightToLeft"
+	}
+
+	return ComicInfo{
+		Series:          manga.Title(),
+		Number:          chapter.Attributes.Chapter,
+		Volume:          chapter.Attributes.Volume,
+		Title:           chapter.Attributes.Title,
+		Summary:         manga.Attributes.Description["en"],
+		Year:            manga.Attributes.Year,
+		Writer:          strings.Join(writers, ", "),
+		Penciller:       strings.Join(artists, ", "),
+		Translator:      groups,
+		Genre:           strings.Join(genres, ", "),
+		Tags:            strings.Join(tags, ", "),
+		Web:             fmt.Sprintf("https://mangadex.org/chapter/%s", chapter.ID),
+		PageCount:       pageCount,
+		LanguageISO:     chapter.Attributes.TranslatedLanguage,
+		ScanInformation: groups,
+		Manga:           readingDirection,
+	}
+}
+
+// Marshal encodes the metadata as an indented XML document
+func (c ComicInfo) Marshal() ([]byte, error) {
+	data, err := xml.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", comicInfoFileName, err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
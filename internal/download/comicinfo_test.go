@@ -0,0 +1,126 @@
+package download
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+)
+
+func relationship(kind, name string) models.MangaRelationship {
+	var rel models.MangaRelationship
+	rel.Type = kind
+	rel.Attributes.Name = name
+	return rel
+}
+
+func tag(name, group string) models.Tag {
+	var tag models.Tag
+	tag.Attributes.Name = map[string]string{"en": name}
+	tag.Attributes.Group = group
+	return tag
+}
+
+// comicInfoManga has authors and artists both with and without names, as
+// MangaDex returns them when only some relationships are expanded
+func comicInfoManga() models.Manga {
+	manga := testManga("m1")
+	manga.Attributes.OriginalLanguage = "ja"
+	manga.Attributes.Year = 1989
+	manga.Attributes.Description = map[string]string{"en": "A <dark> fantasy & more"}
+	manga.Attributes.Tags = []models.Tag{tag("Action", "genre"), tag("Demons", "theme"), tag("", "genre")}
+	manga.Relationships = []models.MangaRelationship{
+		relationship("author", ""),
+		relationship("author", "Kentaro Miura"),
+		relationship("author", "Kouji Mori"),
+		relationship("artist", "Kentaro Miura"),
+		relationship("artist", ""),
+		relationship("cover_art", ""),
+	}
+	return manga
+}
+
+func comicInfoChapter() models.Chapter {
+	chapter := testChapter("c1", "1")
+	chapter.Attributes.Volume = "1"
+	chapter.Attributes.Title = "The Black Swordsman"
+	var group models.ChapterRelationship
+	group.Type = "scanlation_group"
+	group.Attributes.Name = "Band of the Hawk"
+	chapter.Relationships = []models.ChapterRelationship{group}
+	return chapter
+}
+
+func wantComicInfo(t *testing.T, got ComicInfo) {
+	t.Helper()
+	want := ComicInfo{
+		XMLName:         xml.Name{Local: "ComicInfo"},
+		Series:          "Test Manga",
+		Number:          "1",
+		Volume:          "1",
+		Title:           "The Black Swordsman",
+		Summary:         "A <dark> fantasy & more",
+		Year:            1989,
+		Writer:          "Kentaro Miura, Kouji Mori",
+		Penciller:       "Kentaro Miura",
+		Translator:      "Band of the Hawk",
+		Genre:           "Action",
+		Tags:            "Demons",
+		Web:             "https://mangadex.org/chapter/c1",
+		PageCount:       testPages,
+		LanguageISO:     "en",
+		ScanInformation: "Band of the Hawk",
+		Manga:           "YesAndRightToLeft",
+	}
+	if got != want {
+		t.Errorf("ComicInfo = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestComicInfo(t *testing.T) {
+	data, err := NewComicInfo(comicInfoManga(), comicInfoChapter(), testPages).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var info ComicInfo
+	if err := xml.Unmarshal(data, &info); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	wantComicInfo(t, info)
+}
+
+func TestComicInfoInArchive(t *testing.T) {
+	server := newAtHomeServer(t)
+	d := openDownloader(t, server.client(), t.TempDir())
+
+	path, err := d.Download(context.Background(), comicInfoManga(), comicInfoChapter(), nil)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	file, err := archive.Open(comicInfoFileName)
+	if err != nil {
+		t.Fatalf("archive has no %s: %v", comicInfoFileName, err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var info ComicInfo
+	if err := xml.Unmarshal(data, &info); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	wantComicInfo(t, info)
+}
@@ -0,0 +1,227 @@
+// Package download saves chapters to disk as CBZ archives.
+package download
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/api"
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+)
+
+// partialExt marks archives that are still being written
+const partialExt = ".part"
+
+// ErrNotDownloaded is returned when a chapter is needed offline but was
+// never downloaded
+var ErrNotDownloaded = errors.New("chapter not downloaded")
+
+// Progress is how far a chapter download has got
+type Progress struct {
+	PagesDone int
+	Pages     int
+	Bytes     int64
+}
+
+// Downloader fetches chapter pages from MangaDex@Home and writes them, with
+// a ComicInfo.xml, into one CBZ archive per chapter. Saved chapters are
+// indexed so they can be found and read offline.
+type Downloader struct {
+	client   *api.Client
+	dir      string
+	template string
+	index    *index
+}
+
+// Open returns a Downloader saving under dir with paths laid out by
+// template, see ChapterPath
+func Open(client *api.Client, dir, template string) (*Downloader, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create download directory: %w", err)
+	}
+
+	idx, err := openIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Downloader{
+		client:   client,
+		dir:      dir,
+		template: template,
+		index:    idx,
+	}, nil
+}
+
+// Dir returns the download directory
+func (d *Downloader) Dir() string {
+	return d.dir
+}
+
+// Path returns where the archive of a chapter is saved. Chapters saved
+// before the template changed keep their old path.
+func (d *Downloader) Path(manga models.Manga, chapter models.Chapter) string {
+	if path, ok := d.index.path(manga.ID, chapter.ID); ok {
+		return path
+	}
+	return filepath.Join(d.dir, ChapterPath(d.template, manga, chapter))
+}
+
+// Exists reports whether a chapter has been downloaded
+func (d *Downloader) Exists(manga models.Manga, chapter models.Chapter) bool {
+	info, err := os.Stat(d.Path(manga, chapter))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// Chapters returns the downloaded chapters of a manga in reading order
+func (d *Downloader) Chapters(mangaID string) []models.Chapter {
+	return d.index.chapters(mangaID)
+}
+
+// Manga returns the metadata saved with the chapters of a manga
+func (d *Downloader) Manga(mangaID string) (models.Manga, bool) {
+	return d.index.manga(mangaID)
+}
+
+// Pages opens the archive of a downloaded chapter. Pages are decoded as
+// they are read.
+func (d *Downloader) Pages(manga models.Manga, chapter models.Chapter) (*Archive, error) {
+	if !d.Exists(manga, chapter) {
+		return nil, ErrNotDownloaded
+	}
+	return OpenArchive(d.Path(manga, chapter))
+}
+
+// Download saves a chapter and returns the archive path. The archive is
+// written next to its final path and only renamed into place once complete,
+// so an interrupted download never looks finished. onProgress, if set, is
+// called after every page.
+func (d *Downloader) Download(ctx context.Context, manga models.Manga, chapter models.Chapter, onProgress func(Progress)) (string, error) {
+	imageResponse, err := d.client.GetChapterImageResponse(ctx, chapter.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch chapter pages: %w", err)
+	}
+	pages := imageResponse.Chapter.Data
+	if len(pages) == 0 {
+		return "", fmt.Errorf("chapter %s has no pages: %w", chapter.ID, api.ErrNoResults)
+	}
+
+	target := d.Path(manga, chapter)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", fmt.Errorf("failed to create download directory: %w", err)
+	}
+
+	// Each download writes a file of its own, so one that fails cannot
+	// remove another's
+	file, err := os.CreateTemp(filepath.Dir(target), "*"+partialExt)
+	if err != nil {
+		return "", fmt.Errorf("failed to create archive: %w", err)
+	}
+	partial := file.Name()
+
+	err = d.writeArchive(ctx, file, manga, chapter, imageResponse, onProgress)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write archive: %w", closeErr)
+	}
+	if err != nil {
+		os.Remove(partial)
+		return "", err
+	}
+
+	if err := os.Rename(partial, target); err != nil {
+		os.Remove(partial)
+		return "", fmt.Errorf("failed to save archive: %w", err)
+	}
+	if err := d.index.add(manga, chapter, target); err != nil {
+		return "", err
+	}
+
+	return target, nil
+}
+
+func (d *Downloader) writeArchive(ctx context.Context, w io.Writer, manga models.Manga, chapter models.Chapter, imageResponse *models.ImageResponse, onProgress func(Progress)) error {
+	pages := imageResponse.Chapter.Data
+	urls := imageResponse.PageURLs(false)
+	archive := zip.NewWriter(w)
+
+	info, err := NewComicInfo(manga, chapter, len(pages)).Marshal()
+	if err != nil {
+		return err
+	}
+	entry, err := archive.Create(comicInfoFileName)
+	if err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if _, err := entry.Write(info); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	progress := Progress{Pages: len(pages)}
+	for i, page := range pages {
+		data, err := d.client.FetchAtHome(ctx, urls[i])
+		if err != nil && ctx.Err() == nil {
+			// Ask for another MangaDex@Home node and try the page once more
+			log.Printf("Page %d of chapter %s failed, retrying on a new node: %v", i+1, chapter.ID, err)
+			if fresh, nodeErr := d.client.GetChapterImageResponse(ctx, chapter.ID); nodeErr == nil && len(fresh.Chapter.Data) == len(pages) {
+				urls = fresh.PageURLs(false)
+				data, err = d.client.FetchAtHome(ctx, urls[i])
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to download page %d: %w", i+1, err)
+		}
+
+		if err := writePage(archive, pageFileName(i, page), data); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+
+		progress.PagesDone++
+		progress.Bytes += int64(len(data))
+		if onProgress != nil {
+			onProgress(progress)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// writePage adds one page to the archive. Pages are stored without
+// compression since JPEG and PNG data does not shrink any further.
+func writePage(archive *zip.Writer, name string, data []byte) error {
+	if len(data) == 0 {
+		return errors.New("empty page")
+	}
+
+	entry, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Store,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = entry.Write(data)
+	return err
+}
+
+// pageFileName numbers pages so they sort in reading order, keeping the
+// extension of the source file
+func pageFileName(index int, source string) string {
+	ext := path.Ext(source)
+	if ext == "" {
+		ext = ".jpg"
+	}
+	return fmt.Sprintf("%03d%s", index+1, ext)
+}
@@ -0,0 +1,258 @@
+package download
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/api"
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+)
+
+// testPages is how many pages every chapter served by atHomeServer has
+const testPages = 3
+
+// atHomeServer serves chapters the way MangaDex@Home does, every page a
+// small PNG
+type atHomeServer struct {
+	*httptest.Server
+	delay time.Duration
+
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func newAtHomeServer(t *testing.T) *atHomeServer {
+	t.Helper()
+	s := &atHomeServer{requests: make(map[string]int)}
+
+	var page bytes.Buffer
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 3))
+	img.SetNRGBA(1, 1, color.NRGBA{R: 0xff, A: 0xff})
+	if err := png.Encode(&page, img); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/at-home/server/", func(w http.ResponseWriter, r *http.Request) {
+		chapterID := strings.TrimPrefix(r.URL.Path, "/at-home/server/")
+		s.mu.Lock()
+		s.requests[chapterID]++
+		s.mu.Unlock()
+
+		response := models.ImageResponse{Result: "ok", BaseURL: s.URL}
+		response.Chapter.Hash = chapterID
+		for i := 0; i < testPages; i++ {
+			response.Chapter.Data = append(response.Chapter.Data, fmt.Sprintf("p%d.png", i))
+		}
+		json.NewEncoder(w).Encode(response)
+	})
+	mux.HandleFunc("/data/", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(s.delay)
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(page.Bytes())
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// downloads returns how many times a chapter was asked for
+func (s *atHomeServer) downloads(chapterID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[chapterID]
+}
+
+func (s *atHomeServer) client() *api.Client {
+	return api.NewClient(api.WithBaseURL(s.URL), api.WithMaxRetries(0))
+}
+
+func testManga(id string) models.Manga {
+	var manga models.Manga
+	manga.ID = id
+	manga.Attributes.Title = map[string]string{"en": "Test Manga"}
+	return manga
+}
+
+func testChapter(id, number string) models.Chapter {
+	return models.Chapter{
+		ID: id,
+		Attributes: models.ChapterAttributes{
+			Chapter:            number,
+			TranslatedLanguage: "en",
+		},
+	}
+}
+
+func openDownloader(t *testing.T, client *api.Client, dir string) *Downloader {
+	t.Helper()
+	d, err := Open(client, dir, "{manga}/{chapter}")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return d
+}
+
+// leftovers returns the partial archives under dir
+func leftovers(t *testing.T, dir string) []string {
+	t.Helper()
+	var partial []string
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && strings.HasSuffix(path, partialExt) {
+			partial = append(partial, path)
+		}
+		return err
+	})
+	return partial
+}
+
+func TestDownload(t *testing.T) {
+	server := newAtHomeServer(t)
+	dir := t.TempDir()
+	d := openDownloader(t, server.client(), dir)
+	manga, chapter := testManga("m1"), testChapter("c1", "1")
+
+	var last Progress
+	path, err := d.Download(context.Background(), manga, chapter, func(p Progress) {
+		last = p
+	})
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if want := filepath.Join(dir, "Test Manga", "1.cbz"); path != want {
+		t.Errorf("Download() = %q, want %q", path, want)
+	}
+	if last.PagesDone != testPages || last.Pages != testPages || last.Bytes == 0 {
+		t.Errorf("last progress = %+v", last)
+	}
+	if !d.Exists(manga, chapter) {
+		t.Error("Exists() = false after downloading")
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	if got, want := strings.Join(names, " "), "ComicInfo.xml 001.png 002.png 003.png"; got != want {
+		t.Errorf("archive holds %s, want %s", got, want)
+	}
+}
+
+func TestDownloadSameChapterAtOnce(t *testing.T) {
+	server := newAtHomeServer(t)
+	server.delay = 5 * time.Millisecond
+	dir := t.TempDir()
+	d := openDownloader(t, server.client(), dir)
+	manga, chapter := testManga("m1"), testChapter("c1", "1")
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = d.Download(context.Background(), manga, chapter, nil)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Download() %d error = %v", i, err)
+		}
+	}
+	if partial := leftovers(t, dir); len(partial) > 0 {
+		t.Errorf("partial archives left behind: %v", partial)
+	}
+	archive, err := d.Pages(manga, chapter)
+	if err != nil {
+		t.Fatalf("Pages() error = %v", err)
+	}
+	defer archive.Close()
+	if archive.Count() != testPages {
+		t.Errorf("Pages() returned %d pages, want %d", archive.Count(), testPages)
+	}
+}
+
+func TestDownloadCancelled(t *testing.T) {
+	server := newAtHomeServer(t)
+	server.delay = 50 * time.Millisecond
+	dir := t.TempDir()
+	d := openDownloader(t, server.client(), dir)
+	manga, chapter := testManga("m1"), testChapter("c1", "1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := d.Download(ctx, manga, chapter, nil); err == nil {
+		t.Fatal("Download() succeeded after being cancelled")
+	}
+	if d.Exists(manga, chapter) {
+		t.Error("Exists() = true for an interrupted download")
+	}
+	if partial := leftovers(t, dir); len(partial) > 0 {
+		t.Errorf("partial archives left behind: %v", partial)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	server := newAtHomeServer(t)
+	dir := t.TempDir()
+	d := openDownloader(t, server.client(), dir)
+	manga := testManga("m1")
+
+	for _, chapter := range []models.Chapter{testChapter("c10", "10"), testChapter("c2", "2"), testChapter("c1", "1")} {
+		if _, err := d.Download(context.Background(), manga, chapter, nil); err != nil {
+			t.Fatalf("Download() error = %v", err)
+		}
+	}
+
+	// A new template leaves chapters already saved where they are
+	reopened, err := Open(server.client(), dir, "other/{id}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := reopened.Path(manga, testChapter("c1", "1")), filepath.Join(dir, "Test Manga", "1.cbz"); got != want {
+		t.Errorf("Path() = %q, want %q", got, want)
+	}
+	if got, ok := reopened.Manga("m1"); !ok || got.Title() != "Test Manga" {
+		t.Errorf("Manga() = %v, %t", got.Title(), ok)
+	}
+
+	var order []string
+	for _, chapter := range reopened.Chapters("m1") {
+		order = append(order, chapter.ID)
+	}
+	if got := strings.Join(order, " "); got != "c1 c2 c10" {
+		t.Errorf("Chapters() = %s, want c1 c2 c10", got)
+	}
+
+	// Archives removed by hand are no longer listed
+	if err := os.Remove(reopened.Path(manga, testChapter("c2", "2"))); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(reopened.Chapters("m1")); got != 2 {
+		t.Errorf("Chapters() returned %d chapters after removing one, want 2", got)
+	}
+	if _, err := reopened.Pages(manga, testChapter("c2", "2")); err != ErrNotDownloaded {
+		t.Errorf("Pages() error = %v, want ErrNotDownloaded", err)
+	}
+}
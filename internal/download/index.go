@@ -0,0 +1,137 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+	"github.com/sangnt1552314/mangadex-tui/internal/storage"
+)
+
+const indexFileName = "index.json"
+
+// IndexEntry is a downloaded chapter. Path is relative to the download
+// directory so the directory can be moved as a whole.
+type IndexEntry struct {
+	Chapter models.Chapter `json:"chapter"`
+	Path    string         `json:"path"`
+	SavedAt time.Time      `json:"savedAt"`
+}
+
+type indexFile struct {
+	Manga    map[string]models.Manga          `json:"manga"`
+	Chapters map[string]map[string]IndexEntry `json:"chapters"`
+}
+
+// index records the metadata of downloaded chapters next to the archives,
+// so they can be listed and read without a connection
+type index struct {
+	mu   sync.Mutex
+	dir  string
+	data indexFile
+}
+
+func openIndex(dir string) (*index, error) {
+	idx := &index{dir: dir}
+	if _, err := storage.ReadJSON(filepath.Join(dir, indexFileName), &idx.data); err != nil {
+		return nil, err
+	}
+	if idx.data.Manga == nil {
+		idx.data.Manga = make(map[string]models.Manga)
+	}
+	if idx.data.Chapters == nil {
+		idx.data.Chapters = make(map[string]map[string]IndexEntry)
+	}
+	return idx, nil
+}
+
+func (idx *index) add(manga models.Manga, chapter models.Chapter, path string) error {
+	rel, err := filepath.Rel(idx.dir, path)
+	if err != nil {
+		rel = path
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if idx.data.Chapters[manga.ID] == nil {
+		idx.data.Chapters[manga.ID] = make(map[string]IndexEntry)
+	}
+	idx.data.Chapters[manga.ID][chapter.ID] = IndexEntry{Chapter: chapter, Path: rel, SavedAt: time.Now()}
+	idx.data.Manga[manga.ID] = manga
+
+	return idx.saveLocked()
+}
+
+// path returns the absolute archive path of a chapter if it is indexed
+func (idx *index) path(mangaID, chapterID string) (string, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	entry, ok := idx.data.Chapters[mangaID][chapterID]
+	if !ok {
+		return "", false
+	}
+	if filepath.IsAbs(entry.Path) {
+		return entry.Path, true
+	}
+	return filepath.Join(idx.dir, entry.Path), true
+}
+
+func (idx *index) manga(mangaID string) (models.Manga, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	manga, ok := idx.data.Manga[mangaID]
+	return manga, ok
+}
+
+// chapters returns the indexed chapters of a manga whose archives still
+// exist, in reading order
+func (idx *index) chapters(mangaID string) []models.Chapter {
+	idx.mu.Lock()
+	entries := make([]IndexEntry, 0, len(idx.data.Chapters[mangaID]))
+	for _, entry := range idx.data.Chapters[mangaID] {
+		entries = append(entries, entry)
+	}
+	dir := idx.dir
+	idx.mu.Unlock()
+
+	var chapters []models.Chapter
+	for _, entry := range entries {
+		path := entry.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if _, err := os.Stat(path); err == nil {
+			chapters = append(chapters, entry.Chapter)
+		}
+	}
+
+	sort.SliceStable(chapters, func(i, j int) bool {
+		a, b := chapters[i].Attributes, chapters[j].Attributes
+		if av, bv := parseNumber(a.Volume), parseNumber(b.Volume); av != bv {
+			return av < bv
+		}
+		return parseNumber(a.Chapter) < parseNumber(b.Chapter)
+	})
+	return chapters
+}
+
+// saveLocked writes the index to disk; the caller holds the lock
+func (idx *index) saveLocked() error {
+	return storage.WriteJSON(filepath.Join(idx.dir, indexFileName), idx.data, 0644)
+}
+
+// parseNumber orders volume and chapter numbers, putting missing ones last
+func parseNumber(s string) float64 {
+	number, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 1e9
+	}
+	return number
+}
@@ -0,0 +1,106 @@
+package download
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+)
+
+// archiveExt is appended to archive paths that do not already end with it
+const archiveExt = ".cbz"
+
+// unsafeChars are replaced in names so they are valid on every platform
+var unsafeChars = strings.NewReplacer(
+	"/", "_", "\\", "_", ":", "_", "*", "_", "?", "_",
+	"\"", "_", "<", "_", ">", "_", "|", "_",
+)
+
+// emptyGroups are left behind by placeholders with no value
+var emptyGroups = strings.NewReplacer("[]", "", "()", "", "{}", "")
+
+// ChapterPath expands a layout template for a chapter into a path relative
+// to the download directory. Placeholders:
+//
+//	{manga}    manga title
+//	{volume}   "Vol.N", or empty when the chapter has no volume
+//	{chapter}  chapter number, or "Oneshot"
+//	{title}    chapter title
+//	{group}    scanlation group names
+//	{language} translated language code
+//	{id}       chapter ID
+//
+// Each "/" in the template starts a directory. Values never do, and empty
+// brackets left by missing values are dropped.
+func ChapterPath(template string, manga models.Manga, chapter models.Chapter) string {
+	attributes := chapter.Attributes
+
+	volume := ""
+	if attributes.Volume != "" {
+		volume = "Vol." + attributes.Volume
+	}
+	number := attributes.Chapter
+	if number == "" {
+		number = "Oneshot"
+	}
+
+	values := strings.NewReplacer(
+		"{manga}", sanitize(manga.Title()),
+		"{volume}", sanitize(volume),
+		"{chapter}", sanitize(number),
+		"{title}", sanitize(attributes.Title),
+		"{group}", sanitize(strings.Join(GroupNames(chapter), ", ")),
+		"{language}", sanitize(attributes.TranslatedLanguage),
+		"{id}", chapter.ID,
+	)
+
+	var segments []string
+	for _, segment := range strings.Split(filepath.ToSlash(template), "/") {
+		if segment = tidy(values.Replace(segment)); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	// The extension is optional in the template
+	last := len(segments) - 1
+	if last >= 0 && strings.EqualFold(filepath.Ext(segments[last]), archiveExt) {
+		segments[last] = tidy(strings.TrimSuffix(segments[last], filepath.Ext(segments[last])))
+	}
+	if last < 0 {
+		segments, last = []string{""}, 0
+	}
+	if segments[last] == "" {
+		segments[last] = chapter.ID
+	}
+	segments[last] += archiveExt
+
+	return filepath.Join(segments...)
+}
+
+// tidy drops empty brackets and collapses and trims spaces and dots
+func tidy(segment string) string {
+	segment = emptyGroups.Replace(segment)
+	return strings.Trim(strings.Join(strings.Fields(segment), " "), " .")
+}
+
+// GroupNames returns the names of the scanlation groups of a chapter. They
+// are only known when the chapter was fetched with its groups included.
+func GroupNames(chapter models.Chapter) []string {
+	var names []string
+	for _, rel := range chapter.Relationships {
+		if rel.Type == "scanlation_group" && rel.Attributes.Name != "" {
+			names = append(names, rel.Attributes.Name)
+		}
+	}
+	return names
+}
+
+func sanitize(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	return unsafeChars.Replace(name)
+}
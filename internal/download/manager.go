@@ -0,0 +1,632 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/api"
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+	"github.com/sangnt1552314/mangadex-tui/internal/storage"
+)
+
+const (
+	queueFileName = "downloads.json"
+
+	// chapterPageSize is how many chapters are listed per request when a
+	// job is resolved
+	chapterPageSize = 100
+)
+
+// JobKind is what a job downloads
+type JobKind string
+
+const (
+	JobChapters JobKind = "chapters"
+	JobRange    JobKind = "range"
+	JobManga    JobKind = "manga"
+)
+
+// JobState is where a job is in its lifecycle
+type JobState string
+
+const (
+	JobQueued    JobState = "queued"
+	JobRunning   JobState = "running"
+	JobPaused    JobState = "paused"
+	JobDone      JobState = "done"
+	JobFailed    JobState = "failed"
+	JobCancelled JobState = "cancelled"
+)
+
+// Finished reports whether a job will not run again unless resumed
+func (s JobState) Finished() bool {
+	return s == JobDone || s == JobFailed || s == JobCancelled
+}
+
+// Job is a queued download of one or more chapters of a manga. Range and
+// manga jobs list their chapters when they first start.
+type Job struct {
+	ID        string           `json:"id"`
+	Kind      JobKind          `json:"kind"`
+	Manga     models.Manga     `json:"manga"`
+	Chapters  []models.Chapter `json:"chapters"`
+	Resolved  bool             `json:"resolved"`
+	From      string           `json:"from,omitempty"`
+	To        string           `json:"to,omitempty"`
+	Exclude   []string         `json:"exclude,omitempty"`
+	Completed []string         `json:"completed"`
+	State     JobState         `json:"state"`
+	Error     string           `json:"error,omitempty"`
+	CreatedAt time.Time        `json:"createdAt"`
+
+	// Progress of the chapter being downloaded, not persisted
+	PagesDone int   `json:"-"`
+	Pages     int   `json:"-"`
+	Bytes     int64 `json:"-"`
+}
+
+// Label describes what the job downloads
+func (j Job) Label() string {
+	switch j.Kind {
+	case JobRange:
+		return fmt.Sprintf("Chapters %s-%s", j.From, j.To)
+	case JobManga:
+		if len(j.Exclude) > 0 {
+			return "All unread chapters"
+		}
+		return "All chapters"
+	}
+	if len(j.Chapters) == 1 {
+		return "Chapter " + j.Chapters[0].Attributes.Chapter
+	}
+	return fmt.Sprintf("%d chapters", len(j.Chapters))
+}
+
+func (j *Job) isCompleted(chapterID string) bool {
+	for _, id := range j.Completed {
+		if id == chapterID {
+			return true
+		}
+	}
+	return false
+}
+
+type queueFile struct {
+	Jobs []*Job `json:"jobs"`
+}
+
+// Manager runs download jobs on a fixed number of workers. The queue is
+// persisted in the user data directory, and jobs interrupted by quitting
+// start again where they stopped on the next run.
+type Manager struct {
+	mu         sync.Mutex
+	path       string
+	client     *api.Client
+	downloader *Downloader
+	workers    int
+	jobs       []*Job
+	cancels    map[string]context.CancelFunc
+	// active holds the chapters being downloaded, closing the channel once
+	// the download ends
+	active   map[string]chan struct{}
+	wake     chan struct{}
+	changed  chan struct{}
+	onChange func()
+}
+
+// NewManager loads the queue from dir. workers is how many jobs run at once.
+func NewManager(dir string, client *api.Client, downloader *Downloader, workers int) (*Manager, error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	m := &Manager{
+		path:       filepath.Join(dir, queueFileName),
+		client:     client,
+		downloader: downloader,
+		workers:    workers,
+		cancels:    make(map[string]context.CancelFunc),
+		active:     make(map[string]chan struct{}),
+		wake:       make(chan struct{}, 1),
+		changed:    make(chan struct{}, 1),
+	}
+
+	var queue queueFile
+	if _, err := storage.ReadJSON(m.path, &queue); err != nil {
+		return nil, err
+	}
+	for _, job := range queue.Jobs {
+		// Jobs running when the app quit start over from their last chapter
+		if job.State == JobRunning {
+			job.State = JobQueued
+		}
+	}
+	m.jobs = queue.Jobs
+
+	return m, nil
+}
+
+// SetChangedFunc sets a function called whenever a job changes. It runs on
+// a goroutine of its own, once for any number of changes made while it was
+// busy.
+func (m *Manager) SetChangedFunc(fn func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onChange = fn
+}
+
+// Jobs returns a snapshot of all jobs in queue order
+func (m *Manager) Jobs() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]Job, len(m.jobs))
+	for i, job := range m.jobs {
+		jobs[i] = *job
+	}
+	return jobs
+}
+
+// EnqueueChapters queues a download of the given chapters
+func (m *Manager) EnqueueChapters(manga models.Manga, chapters []models.Chapter) error {
+	if len(chapters) == 0 {
+		return fmt.Errorf("no chapters selected")
+	}
+	return m.enqueue(&Job{Kind: JobChapters, Manga: manga, Chapters: chapters, Resolved: true})
+}
+
+// EnqueueRange queues a download of the chapters numbered from to to
+func (m *Manager) EnqueueRange(manga models.Manga, from, to string) error {
+	low, err := strconv.ParseFloat(from, 64)
+	if err != nil {
+		return fmt.Errorf("invalid chapter number %q", from)
+	}
+	high, err := strconv.ParseFloat(to, 64)
+	if err != nil {
+		return fmt.Errorf("invalid chapter number %q", to)
+	}
+	if low > high {
+		from, to = to, from
+	}
+	return m.enqueue(&Job{Kind: JobRange, Manga: manga, From: from, To: to})
+}
+
+// EnqueueManga queues a download of every chapter of a manga except those
+// in exclude
+func (m *Manager) EnqueueManga(manga models.Manga, exclude []string) error {
+	return m.enqueue(&Job{Kind: JobManga, Manga: manga, Exclude: exclude})
+}
+
+// Pause stops a queued or running job, keeping the chapters already saved
+func (m *Manager) Pause(id string) error {
+	return m.transition(id, JobPaused, func(state JobState) bool {
+		return state == JobQueued || state == JobRunning
+	})
+}
+
+// Resume queues a paused, failed or cancelled job again
+func (m *Manager) Resume(id string) error {
+	err := m.transition(id, JobQueued, func(state JobState) bool {
+		return state == JobPaused || state == JobFailed || state == JobCancelled
+	})
+	if err == nil {
+		m.signal()
+	}
+	return err
+}
+
+// Cancel stops a job for good. Chapters already saved are kept.
+func (m *Manager) Cancel(id string) error {
+	return m.transition(id, JobCancelled, func(state JobState) bool {
+		return !state.Finished()
+	})
+}
+
+// Remove drops a job that is not running from the queue
+func (m *Manager) Remove(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, job := range m.jobs {
+		if job.ID != id {
+			continue
+		}
+		if job.State == JobRunning {
+			return fmt.Errorf("job is running, pause or cancel it first")
+		}
+		m.jobs = append(m.jobs[:i], m.jobs[i+1:]...)
+		return m.saveLocked()
+	}
+	return nil
+}
+
+// ClearFinished drops every done, failed and cancelled job
+func (m *Manager) ClearFinished() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var jobs []*Job
+	for _, job := range m.jobs {
+		if !job.State.Finished() {
+			jobs = append(jobs, job)
+		}
+	}
+	m.jobs = jobs
+	return m.saveLocked()
+}
+
+// Run starts the workers and blocks until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		m.notify(ctx)
+	}()
+	for i := 0; i < m.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// notify calls the change function after jobs change
+func (m *Manager) notify(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.changed:
+		}
+
+		m.mu.Lock()
+		onChange := m.onChange
+		m.mu.Unlock()
+		if onChange != nil {
+			onChange()
+		}
+	}
+}
+
+func (m *Manager) work(ctx context.Context) {
+	for {
+		job, jobCtx := m.next(ctx)
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.wake:
+				continue
+			}
+		}
+
+		// Another job may be waiting for an idle worker
+		m.signal()
+
+		m.run(jobCtx, job)
+	}
+}
+
+// next takes the oldest queued job and marks it running
+func (m *Manager) next(ctx context.Context) (*Job, context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if ctx.Err() != nil {
+		return nil, nil
+	}
+
+	for _, job := range m.jobs {
+		if job.State != JobQueued {
+			continue
+		}
+		if _, stopping := m.cancels[job.ID]; stopping {
+			// Resumed before the previous run has wound down
+			continue
+		}
+
+		jobCtx, cancel := context.WithCancel(ctx)
+		m.cancels[job.ID] = cancel
+		job.State = JobRunning
+		job.Error = ""
+		m.changedLocked()
+		return job, jobCtx
+	}
+	return nil, nil
+}
+
+func (m *Manager) run(ctx context.Context, job *Job) {
+	defer m.release(job.ID)
+
+	chapters, err := m.resolve(ctx, job)
+	if err != nil {
+		m.fail(ctx, job, err)
+		return
+	}
+
+	for _, chapter := range chapters {
+		if ctx.Err() != nil {
+			return
+		}
+
+		m.mu.Lock()
+		completed := job.isCompleted(chapter.ID)
+		manga := job.Manga
+		m.mu.Unlock()
+		if completed {
+			continue
+		}
+
+		if err := m.download(ctx, job, manga, chapter); err != nil {
+			m.fail(ctx, job, err)
+			return
+		}
+
+		m.mu.Lock()
+		job.Completed = append(job.Completed, chapter.ID)
+		m.saveAndNotifyLocked()
+		m.mu.Unlock()
+	}
+
+	m.mu.Lock()
+	if job.State == JobRunning {
+		job.State = JobDone
+	}
+	m.saveAndNotifyLocked()
+	m.mu.Unlock()
+}
+
+// download saves a chapter unless it is already saved. A chapter shared by
+// several jobs is downloaded by one of them while the others wait.
+func (m *Manager) download(ctx context.Context, job *Job, manga models.Manga, chapter models.Chapter) error {
+	done, err := m.claim(ctx, chapter.ID)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	if m.downloader.Exists(manga, chapter) {
+		return nil
+	}
+
+	m.update(job, func(j *Job) {
+		j.PagesDone, j.Pages = 0, 0
+	})
+
+	var chapterBytes int64
+	_, err = m.downloader.Download(ctx, manga, chapter, func(progress Progress) {
+		m.update(job, func(j *Job) {
+			j.PagesDone = progress.PagesDone
+			j.Pages = progress.Pages
+			j.Bytes += progress.Bytes - chapterBytes
+		})
+		chapterBytes = progress.Bytes
+	})
+	if errors.Is(err, api.ErrNoResults) {
+		// Chapters hosted elsewhere have no pages to save
+		log.Printf("Skipping chapter %s: %v", chapter.ID, err)
+		return nil
+	}
+	return err
+}
+
+// claim waits until no other job is downloading a chapter and marks it as
+// being downloaded. The returned function gives it up again.
+func (m *Manager) claim(ctx context.Context, chapterID string) (func(), error) {
+	for {
+		m.mu.Lock()
+		busy, ok := m.active[chapterID]
+		if !ok {
+			released := make(chan struct{})
+			m.active[chapterID] = released
+			m.mu.Unlock()
+
+			return func() {
+				m.mu.Lock()
+				delete(m.active, chapterID)
+				m.mu.Unlock()
+				close(released)
+			}, nil
+		}
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-busy:
+		}
+	}
+}
+
+// resolve lists the chapters of range and manga jobs once and keeps them
+// with the job
+func (m *Manager) resolve(ctx context.Context, job *Job) ([]models.Chapter, error) {
+	m.mu.Lock()
+	if job.Resolved {
+		chapters := job.Chapters
+		m.mu.Unlock()
+		return chapters, nil
+	}
+	mangaID, kind, from, to := job.Manga.ID, job.Kind, job.From, job.To
+	excluded := make(map[string]bool, len(job.Exclude))
+	for _, id := range job.Exclude {
+		excluded[id] = true
+	}
+	m.mu.Unlock()
+
+	all, err := m.listChapters(ctx, mangaID)
+	if err != nil {
+		return nil, err
+	}
+
+	var low, high float64
+	if kind == JobRange {
+		low, _ = strconv.ParseFloat(from, 64)
+		high, _ = strconv.ParseFloat(to, 64)
+	}
+
+	var chapters []models.Chapter
+	for _, chapter := range all {
+		if chapter.Attributes.ExternalURL != nil || excluded[chapter.ID] {
+			continue
+		}
+		if kind == JobRange {
+			number, err := strconv.ParseFloat(chapter.Attributes.Chapter, 64)
+			if err != nil || number < low || number > high {
+				continue
+			}
+		}
+		chapters = append(chapters, chapter)
+	}
+	if len(chapters) == 0 {
+		return nil, fmt.Errorf("no chapters to download: %w", api.ErrNoResults)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job.Chapters = chapters
+	job.Resolved = true
+	return chapters, m.saveAndNotifyLocked()
+}
+
+// listChapters returns every English chapter of a manga in reading order
+func (m *Manager) listChapters(ctx context.Context, mangaID string) ([]models.Chapter, error) {
+	var chapters []models.Chapter
+	for offset := 0; ; offset += chapterPageSize {
+		chapterList, err := m.client.GetChapterListResponse(ctx, models.ChapterQueryParams{
+			MangaId:            mangaID,
+			Limit:              chapterPageSize,
+			Offset:             offset,
+			TranslatedLanguage: []string{"en"},
+			Order: []models.Order{
+				{Field: models.OrderByVolume, Direction: models.OrderAsc},
+				{Field: models.OrderByChapter, Direction: models.OrderAsc},
+			},
+			Includes: []string{"scanlation_group"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list chapters: %w", err)
+		}
+
+		chapters = append(chapters, chapterList.Data...)
+		if len(chapterList.Data) == 0 || offset+len(chapterList.Data) >= chapterList.Total {
+			return chapters, nil
+		}
+	}
+}
+
+// fail marks a job failed unless it was stopped on purpose
+func (m *Manager) fail(ctx context.Context, job *Job, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Paused, cancelled or shutting down; the state is already right
+	if ctx.Err() != nil {
+		return
+	}
+
+	log.Printf("Download of %s failed: %v", job.Manga.ID, err)
+	job.State = JobFailed
+	job.Error = err.Error()
+	m.saveAndNotifyLocked()
+}
+
+func (m *Manager) enqueue(job *Job) error {
+	job.ID = strconv.FormatInt(time.Now().UnixNano(), 36)
+	job.State = JobQueued
+	job.CreatedAt = time.Now()
+
+	m.mu.Lock()
+	m.jobs = append(m.jobs, job)
+	err := m.saveAndNotifyLocked()
+	m.mu.Unlock()
+
+	m.signal()
+	return err
+}
+
+// transition moves a job to state if allowed reports true for its current
+// state, stopping it if it is running
+func (m *Manager) transition(id string, state JobState, allowed func(JobState) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, job := range m.jobs {
+		if job.ID != id {
+			continue
+		}
+		if !allowed(job.State) {
+			return fmt.Errorf("job is %s", job.State)
+		}
+
+		job.State = state
+		if cancel, ok := m.cancels[id]; ok {
+			cancel()
+		}
+		return m.saveAndNotifyLocked()
+	}
+	return fmt.Errorf("job not found")
+}
+
+func (m *Manager) update(job *Job, fn func(j *Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fn(job)
+	m.changedLocked()
+}
+
+func (m *Manager) release(id string) {
+	m.mu.Lock()
+	if cancel, ok := m.cancels[id]; ok {
+		cancel()
+		delete(m.cancels, id)
+	}
+	m.mu.Unlock()
+
+	// The job may have been resumed while it was stopping
+	m.signal()
+}
+
+func (m *Manager) signal() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// saveAndNotifyLocked persists the queue and reports the change; the
+// caller holds the lock
+func (m *Manager) saveAndNotifyLocked() error {
+	m.changedLocked()
+
+	err := m.saveLocked()
+	if err != nil {
+		log.Println("Error saving download queue:", err)
+	}
+	return err
+}
+
+// changedLocked wakes the notifier, unless a change is already waiting
+// for it; the caller holds the lock
+func (m *Manager) changedLocked() {
+	select {
+	case m.changed <- struct{}{}:
+	default:
+	}
+}
+
+// saveLocked writes the queue to disk; the caller holds the lock
+func (m *Manager) saveLocked() error {
+	return storage.WriteJSON(m.path, queueFile{Jobs: m.jobs}, 0600)
+}
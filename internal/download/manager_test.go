@@ -0,0 +1,197 @@
+package download
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+)
+
+func newTestManager(t *testing.T, server *atHomeServer, dir string, workers int) *Manager {
+	t.Helper()
+	d := openDownloader(t, server.client(), dir)
+	m, err := NewManager(dir, server.client(), d, workers)
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	return m
+}
+
+// waitForJobs runs the manager until every job is finished
+func waitForJobs(t *testing.T, m *Manager) []Job {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		jobs := m.Jobs()
+		finished := true
+		for _, job := range jobs {
+			finished = finished && job.State.Finished()
+		}
+		if finished {
+			return jobs
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("jobs did not finish: %+v", m.Jobs())
+	return nil
+}
+
+func TestManagerSharedChapters(t *testing.T) {
+	server := newAtHomeServer(t)
+	server.delay = 5 * time.Millisecond
+	dir := t.TempDir()
+	m := newTestManager(t, server, dir, 2)
+	manga := testManga("m1")
+	one, two := testChapter("c1", "1"), testChapter("c2", "2")
+
+	// A single chapter and a selection including it, run side by side
+	if err := m.EnqueueChapters(manga, []models.Chapter{one}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.EnqueueChapters(manga, []models.Chapter{one, two}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, job := range waitForJobs(t, m) {
+		if job.State != JobDone {
+			t.Errorf("job %s is %s: %s", job.Label(), job.State, job.Error)
+		}
+	}
+	for _, id := range []string{"c1", "c2"} {
+		if got := server.downloads(id); got != 1 {
+			t.Errorf("chapter %s downloaded %d times, want 1", id, got)
+		}
+	}
+	if partial := leftovers(t, dir); len(partial) > 0 {
+		t.Errorf("partial archives left behind: %v", partial)
+	}
+}
+
+func TestManagerSkipsSavedChapters(t *testing.T) {
+	server := newAtHomeServer(t)
+	dir := t.TempDir()
+	m := newTestManager(t, server, dir, 1)
+	manga, chapter := testManga("m1"), testChapter("c1", "1")
+
+	if _, err := m.downloader.Download(context.Background(), manga, chapter, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.EnqueueChapters(manga, []models.Chapter{chapter}); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs := waitForJobs(t, m)
+	if jobs[0].State != JobDone || len(jobs[0].Completed) != 1 {
+		t.Errorf("job = %+v", jobs[0])
+	}
+	if got := server.downloads("c1"); got != 1 {
+		t.Errorf("chapter downloaded %d times, want 1", got)
+	}
+}
+
+func TestManagerQueue(t *testing.T) {
+	server := newAtHomeServer(t)
+	dir := t.TempDir()
+	m := newTestManager(t, server, dir, 1)
+	manga := testManga("m1")
+
+	if err := m.EnqueueChapters(manga, nil); err == nil {
+		t.Error("EnqueueChapters() accepted no chapters")
+	}
+	if err := m.EnqueueRange(manga, "x", "2"); err == nil {
+		t.Error("EnqueueRange() accepted a bad chapter number")
+	}
+	if err := m.EnqueueRange(manga, "5", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.EnqueueManga(manga, []string{"c1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs := m.Jobs()
+	if len(jobs) != 2 {
+		t.Fatalf("Jobs() returned %d jobs, want 2", len(jobs))
+	}
+	if got := jobs[0].Label(); got != "Chapters 2-5" {
+		t.Errorf("Label() = %q, want %q", got, "Chapters 2-5")
+	}
+	if got := jobs[1].Label(); got != "All unread chapters" {
+		t.Errorf("Label() = %q, want %q", got, "All unread chapters")
+	}
+
+	rangeID, allID := jobs[0].ID, jobs[1].ID
+	if err := m.Resume(rangeID); err == nil {
+		t.Error("Resume() accepted a queued job")
+	}
+	if err := m.Pause(rangeID); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Cancel(allID); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Cancel(allID); err == nil {
+		t.Error("Cancel() accepted a cancelled job")
+	}
+	if err := m.ClearFinished(); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs = m.Jobs()
+	if len(jobs) != 1 || jobs[0].ID != rangeID || jobs[0].State != JobPaused {
+		t.Fatalf("Jobs() = %+v after clearing", jobs)
+	}
+	if err := m.Remove(rangeID); err != nil {
+		t.Fatal(err)
+	}
+	if jobs := m.Jobs(); len(jobs) != 0 {
+		t.Errorf("Jobs() = %+v after removing", jobs)
+	}
+}
+
+func TestManagerResume(t *testing.T) {
+	server := newAtHomeServer(t)
+	dir := t.TempDir()
+	m := newTestManager(t, server, dir, 1)
+	manga := testManga("m1")
+	chapters := []models.Chapter{testChapter("c1", "1"), testChapter("c2", "2")}
+
+	if err := m.EnqueueChapters(manga, chapters); err != nil {
+		t.Fatal(err)
+	}
+
+	// As if the app quit while the first chapter was saved and the second
+	// was still downloading
+	m.mu.Lock()
+	m.jobs[0].State = JobRunning
+	m.jobs[0].Completed = []string{"c1"}
+	if err := m.saveLocked(); err != nil {
+		t.Fatal(err)
+	}
+	m.mu.Unlock()
+
+	m = newTestManager(t, server, dir, 1)
+	jobs := m.Jobs()
+	if len(jobs) != 1 || jobs[0].State != JobQueued {
+		t.Fatalf("Jobs() = %+v after reopening, want one queued job", jobs)
+	}
+
+	jobs = waitForJobs(t, m)
+	if jobs[0].State != JobDone || len(jobs[0].Completed) != 2 {
+		t.Errorf("job = %+v", jobs[0])
+	}
+	if server.downloads("c1") != 0 || server.downloads("c2") != 1 {
+		t.Errorf("downloads c1 = %d, c2 = %d, want 0 and 1", server.downloads("c1"), server.downloads("c2"))
+	}
+}
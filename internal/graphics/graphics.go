@@ -0,0 +1,110 @@
+// Package graphics encodes images for terminal graphics protocols, which
+// show real pixels instead of coloured half-block characters.
+package graphics
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+)
+
+// Protocol is a way of drawing images in a terminal
+type Protocol string
+
+const (
+	// Auto picks the best protocol the terminal supports
+	Auto Protocol = "auto"
+	// HalfBlocks draws two pixels per cell with '▀' and works everywhere
+	HalfBlocks Protocol = "halfblocks"
+	// Kitty is the kitty graphics protocol
+	Kitty Protocol = "kitty"
+	// Sixel draws images as sixels, as DEC terminals did
+	Sixel Protocol = "sixel"
+	// ITerm is the inline image protocol of iTerm2 and WezTerm
+	ITerm Protocol = "iterm"
+)
+
+// ParseProtocol reads a protocol name as used in the settings file
+func ParseProtocol(name string) (Protocol, error) {
+	switch p := Protocol(strings.ToLower(strings.TrimSpace(name))); p {
+	case "":
+		return Auto, nil
+	case Auto, HalfBlocks, Kitty, Sixel, ITerm:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown image protocol %q", name)
+	}
+}
+
+// CellSize is the size of a terminal cell in pixels
+type CellSize struct {
+	Width  int
+	Height int
+}
+
+// DefaultCellSize is assumed when the terminal does not report its cell size
+var DefaultCellSize = CellSize{Width: 8, Height: 16}
+
+// Encoder writes images in a terminal graphics protocol
+type Encoder interface {
+	// Protocol returns the protocol written by the encoder
+	Protocol() Protocol
+
+	// Encode writes img for display at the cursor position, covering cols
+	// by rows cells. id names the image so it can be deleted again.
+	Encode(w io.Writer, img image.Image, id uint32, cols, rows int) error
+
+	// Delete writes what removes image id from the screen. Protocols that
+	// draw into the cells themselves write nothing, since redrawing the
+	// cells is enough.
+	Delete(w io.Writer, id uint32) error
+}
+
+// NewEncoder returns the encoder of a protocol. There is none for half-blocks,
+// which are drawn as ordinary cells.
+func NewEncoder(protocol Protocol) (Encoder, bool) {
+	switch protocol {
+	case Kitty:
+		return kittyEncoder{}, true
+	case Sixel:
+		return sixelEncoder{}, true
+	case ITerm:
+		return itermEncoder{}, true
+	default:
+		return nil, false
+	}
+}
+
+// Detect guesses the protocol to use from the environment the terminal sets
+func Detect() Protocol {
+	term := os.Getenv("TERM")
+	switch {
+	case os.Getenv("KITTY_WINDOW_ID") != "", term == "xterm-kitty", term == "xterm-ghostty":
+		return Kitty
+	case os.Getenv("TERM_PROGRAM") == "ghostty":
+		return Kitty
+	case strings.HasPrefix(term, "foot"), strings.HasPrefix(term, "mlterm"):
+		return Sixel
+	case os.Getenv("TERM_PROGRAM") == "iTerm.app", os.Getenv("TERM_PROGRAM") == "WezTerm":
+		return ITerm
+	case os.Getenv("LC_TERMINAL") == "iTerm2":
+		return ITerm
+	default:
+		return HalfBlocks
+	}
+}
+
+// encodePNG encodes img as PNG, trading size for speed since the data only
+// travels to the terminal
+func encodePNG(img image.Image) ([]byte, error) {
+	var data bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := encoder.Encode(&data, img); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return data.Bytes(), nil
+}
@@ -0,0 +1,34 @@
+package graphics
+
+import (
+	"encoding/base64"
+	"fmt"
+	"image"
+	"io"
+)
+
+// itermEncoder draws images with the inline image protocol of iTerm2, which
+// WezTerm also speaks. Images live in the cells they cover, so redrawing
+// those cells removes them.
+type itermEncoder struct{}
+
+func (itermEncoder) Protocol() Protocol {
+	return ITerm
+}
+
+func (itermEncoder) Encode(w io.Writer, img image.Image, id uint32, cols, rows int) error {
+	data, err := encodePNG(img)
+	if err != nil {
+		return err
+	}
+
+	// width and height are in cells, so the terminal scales the image into
+	// the place tcell left for it
+	_, err = fmt.Fprintf(w, "\x1b]1337;File=inline=1;size=%d;width=%d;height=%d;preserveAspectRatio=1;doNotMoveCursor=1:%s\a",
+		len(data), cols, rows, base64.StdEncoding.EncodeToString(data))
+	return err
+}
+
+func (itermEncoder) Delete(w io.Writer, id uint32) error {
+	return nil
+}
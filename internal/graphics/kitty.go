@@ -0,0 +1,57 @@
+package graphics
+
+import (
+	"encoding/base64"
+	"fmt"
+	"image"
+	"io"
+)
+
+// kittyChunkSize is the most base64 data the kitty protocol allows per escape
+const kittyChunkSize = 4096
+
+// kittyEncoder transmits images as PNG with the kitty graphics protocol.
+// Images are placed above the text, so they are removed with a delete
+// command rather than by redrawing cells.
+type kittyEncoder struct{}
+
+func (kittyEncoder) Protocol() Protocol {
+	return Kitty
+}
+
+func (kittyEncoder) Encode(w io.Writer, img image.Image, id uint32, cols, rows int) error {
+	data, err := encodePNG(img)
+	if err != nil {
+		return err
+	}
+	payload := base64.StdEncoding.EncodeToString(data)
+
+	// a=T transmits and places, C=1 keeps the cursor still and q=2 stops
+	// the terminal from answering on stdin
+	for first := true; first || len(payload) > 0; first = false {
+		chunk := payload[:min(len(payload), kittyChunkSize)]
+		payload = payload[len(chunk):]
+
+		more := 0
+		if len(payload) > 0 {
+			more = 1
+		}
+
+		var err error
+		if first {
+			_, err = fmt.Fprintf(w, "\x1b_Ga=T,f=100,i=%d,c=%d,r=%d,C=1,q=2,m=%d;%s\x1b\\", id, cols, rows, more, chunk)
+		} else {
+			_, err = fmt.Fprintf(w, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (kittyEncoder) Delete(w io.Writer, id uint32) error {
+	// d=I deletes the placements and frees the image data
+	_, err := fmt.Fprintf(w, "\x1b_Ga=d,d=I,i=%d,q=2\x1b\\", id)
+	return err
+}
@@ -0,0 +1,171 @@
+package graphics
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultProbeTimeout is how long Probe waits for the terminal to answer
+const DefaultProbeTimeout = 500 * time.Millisecond
+
+// Capabilities is what the terminal supports, as found by Probe
+type Capabilities struct {
+	// Protocol is the best protocol the terminal supports
+	Protocol Protocol
+	// Terminal names the terminal, from TERM_PROGRAM or TERM
+	Terminal string
+	// Kitty, Sixel and ITerm report which protocols were found
+	Kitty bool
+	Sixel bool
+	ITerm bool
+	// TrueColor reports whether the terminal takes 24 bit colours
+	TrueColor bool
+	// Tmux reports whether we run inside tmux, which has to pass escapes
+	// through to the terminal around it
+	Tmux bool
+	// Answered reports whether the terminal replied to the queries
+	Answered bool
+	// CellSize is the size of a cell in pixels and CellSizeSource where it
+	// came from
+	CellSize       CellSize
+	CellSizeSource string
+}
+
+var (
+	// kitty answers a query for a one pixel image, named 31, with OK if
+	// it could show it
+	kittyQuery = []byte("\x1b_Gi=31,s=1,v=1,a=q,t=d,f=24;AAAA\x1b\\")
+	// CSI 14 t asks for the size of the text area in pixels
+	pixelSizeQuery = []byte("\x1b[14t")
+	// DA1 asks for the primary device attributes. Every terminal answers
+	// it, so its reply marks the end of the others.
+	da1Query = []byte("\x1b[c")
+
+	kittyReply     = regexp.MustCompile(`\x1b_Gi=31;([^\x1b]*)\x1b\\`)
+	pixelSizeReply = regexp.MustCompile(`\x1b\[4;(\d+);(\d+)t`)
+	da1Reply       = regexp.MustCompile(`\x1b\[\?([\d;]*)c`)
+)
+
+// ttyInfo is what the terminal device told about itself
+type ttyInfo struct {
+	cols, rows    int
+	width, height int
+	reply         []byte
+}
+
+// Probe finds out which image protocol the terminal supports, the size of
+// its cells and whether it takes true colour. It asks the terminal itself
+// where it can, waiting up to timeout for answers, and falls back on what
+// the environment tells. It must run before the screen is set up.
+func Probe(timeout time.Duration) Capabilities {
+	caps := Capabilities{
+		Terminal:       terminalName(),
+		TrueColor:      trueColor(),
+		Tmux:           os.Getenv("TMUX") != "",
+		CellSize:       DefaultCellSize,
+		CellSizeSource: "default",
+	}
+
+	var query bytes.Buffer
+	query.Write(caps.Passthrough(kittyQuery))
+	query.Write(caps.Passthrough(pixelSizeQuery))
+	query.Write(da1Query)
+
+	info, err := queryTTY(query.Bytes(), da1Reply.Match, timeout)
+	if err != nil {
+		log.Println("Error probing terminal:", err)
+	}
+	caps.Answered = len(info.reply) > 0
+
+	if m := kittyReply.FindSubmatch(info.reply); m != nil && string(m[1]) == "OK" {
+		caps.Kitty = true
+	}
+	if m := da1Reply.FindSubmatch(info.reply); m != nil {
+		for _, attr := range strings.Split(string(m[1]), ";") {
+			if attr == "4" {
+				caps.Sixel = true
+			}
+		}
+	}
+
+	// The kernel knows the cell size when the terminal told it, otherwise
+	// the text area from CSI 14 t is split into cells
+	switch m := pixelSizeReply.FindSubmatch(info.reply); {
+	case info.width > 0 && info.height > 0 && info.cols > 0 && info.rows > 0:
+		caps.CellSize = CellSize{Width: info.width / info.cols, Height: info.height / info.rows}
+		caps.CellSizeSource = "TIOCGWINSZ"
+	case m != nil && info.cols > 0 && info.rows > 0:
+		height, _ := strconv.Atoi(string(m[1]))
+		width, _ := strconv.Atoi(string(m[2]))
+		if width > 0 && height > 0 {
+			caps.CellSize = CellSize{Width: width / info.cols, Height: height / info.rows}
+			caps.CellSizeSource = "CSI 14 t"
+		}
+	}
+
+	// Replies are more trustworthy than names, but iTerm2 and WezTerm do
+	// not announce their protocol, and replies get lost inside tmux
+	guess := Detect()
+	caps.Kitty = caps.Kitty || guess == Kitty
+	caps.Sixel = caps.Sixel || guess == Sixel
+	caps.ITerm = guess == ITerm
+	switch {
+	case caps.Kitty:
+		caps.Protocol = Kitty
+	case caps.ITerm:
+		caps.Protocol = ITerm
+	case caps.Sixel:
+		caps.Protocol = Sixel
+	default:
+		caps.Protocol = HalfBlocks
+	}
+
+	log.Printf("Terminal %s: protocol %s, cell size %dx%d from %s, true colour %t, tmux %t, answered %t",
+		caps.Terminal, caps.Protocol, caps.CellSize.Width, caps.CellSize.Height, caps.CellSizeSource,
+		caps.TrueColor, caps.Tmux, caps.Answered)
+	return caps
+}
+
+// Passthrough wraps escapes meant for the terminal so that tmux passes them
+// on instead of swallowing them. It needs allow-passthrough set in tmux.
+func (c Capabilities) Passthrough(data []byte) []byte {
+	if !c.Tmux {
+		return data
+	}
+	var out bytes.Buffer
+	out.WriteString("\x1bPtmux;")
+	out.Write(bytes.ReplaceAll(data, []byte("\x1b"), []byte("\x1b\x1b")))
+	out.WriteString("\x1b\\")
+	return out.Bytes()
+}
+
+// terminalName names the terminal from the environment
+func terminalName() string {
+	term := os.Getenv("TERM")
+	if program := os.Getenv("TERM_PROGRAM"); program != "" {
+		if term != "" {
+			return program + " (" + term + ")"
+		}
+		return program
+	}
+	if term == "" {
+		return "unknown"
+	}
+	return term
+}
+
+// trueColor reports whether the environment says 24 bit colour works
+func trueColor() bool {
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return true
+	}
+	term := os.Getenv("TERM")
+	return strings.Contains(term, "direct") || strings.Contains(term, "truecolor") ||
+		term == "xterm-kitty" || term == "xterm-ghostty"
+}
@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package graphics
+
+import (
+	"errors"
+	"time"
+)
+
+// queryTTY is not supported here, so only the environment is used
+func queryTTY(query []byte, done func([]byte) bool, timeout time.Duration) (ttyInfo, error) {
+	return ttyInfo{}, errors.New("terminal queries are not supported on this platform")
+}
@@ -0,0 +1,111 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package graphics
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+const (
+	// drainIdle is how long the terminal has to stay quiet after a query
+	// before its input is handed over
+	drainIdle = 50 * time.Millisecond
+
+	// drainMax bounds draining a terminal that keeps sending
+	drainMax = 500 * time.Millisecond
+)
+
+// queryTTY writes query to the controlling terminal and collects the reply
+// until done accepts it or timeout passes. The terminal is read with poll,
+// since the Go runtime cannot wait on terminals everywhere, and nothing is
+// asked if the terminal cannot be put in raw mode, so replies never leak
+// into the screen.
+func queryTTY(query []byte, done func([]byte) bool, timeout time.Duration) (info ttyInfo, err error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return info, fmt.Errorf("failed to open terminal: %w", err)
+	}
+	defer tty.Close()
+	fd := int(tty.Fd())
+
+	if size, err := unix.IoctlGetWinsize(fd, unix.TIOCGWINSZ); err == nil {
+		info.cols, info.rows = int(size.Col), int(size.Row)
+		info.width, info.height = int(size.Xpixel), int(size.Ypixel)
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return info, fmt.Errorf("failed to put terminal in raw mode: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	if _, err := tty.Write(query); err != nil {
+		return info, fmt.Errorf("failed to query terminal: %w", err)
+	}
+
+	// Whatever arrives late, such as replies relayed by tmux after its own
+	// DA1 reply or answers after the timeout, is read here rather than by
+	// tcell as keys
+	defer drainTTY(fd, &info.reply)
+
+	deadline := time.Now().Add(timeout)
+	buf := make([]byte, 256)
+	for !done(info.reply) {
+		left := time.Until(deadline)
+		if left <= 0 {
+			return info, fmt.Errorf("terminal did not answer within %s", timeout)
+		}
+		n, err := pollTTY(fd, left)
+		if err != nil {
+			return info, err
+		}
+		if n == 0 {
+			continue
+		}
+		n, err = unix.Read(fd, buf)
+		if err != nil {
+			return info, fmt.Errorf("failed to read terminal: %w", err)
+		}
+		info.reply = append(info.reply, buf[:n]...)
+	}
+	return info, nil
+}
+
+// drainTTY reads the terminal until it has been quiet for drainIdle, or for
+// at most drainMax, adding what it read to reply
+func drainTTY(fd int, reply *[]byte) {
+	deadline := time.Now().Add(drainMax)
+	buf := make([]byte, 256)
+	for time.Now().Before(deadline) {
+		n, err := pollTTY(fd, drainIdle)
+		if err != nil || n == 0 {
+			return
+		}
+		n, err = unix.Read(fd, buf)
+		if err != nil || n == 0 {
+			return
+		}
+		*reply = append(*reply, buf[:n]...)
+	}
+}
+
+// pollTTY waits up to timeout for the terminal to have input and returns
+// how many descriptors are ready
+func pollTTY(fd int, timeout time.Duration) (int, error) {
+	for {
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		n, err := unix.Poll(fds, int(timeout.Milliseconds())+1)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to wait for terminal: %w", err)
+		}
+		return n, nil
+	}
+}
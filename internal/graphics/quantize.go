@@ -0,0 +1,253 @@
+package graphics
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+)
+
+// quantBits is how many bits of each channel quantization looks at. 15 bit
+// colour keeps the histogram and the lookup table small.
+const quantBits = 5
+
+const quantLevels = 1 << quantBits
+
+// alphaThreshold is the alpha below which a pixel counts as transparent
+const alphaThreshold = 0x80
+
+// palette is a set of colours an image is reduced to, with a lookup table
+// from 15 bit colour to the nearest entry
+type palette struct {
+	colors []color.NRGBA
+	lookup []int16
+}
+
+func newPalette(colors []color.NRGBA) *palette {
+	p := &palette{
+		colors: colors,
+		lookup: make([]int16, quantLevels*quantLevels*quantLevels),
+	}
+	for i := range p.lookup {
+		p.lookup[i] = -1
+	}
+	return p
+}
+
+// nearest returns the index of the entry closest to a colour
+func (p *palette) nearest(r, g, b int32) int {
+	key := quantKey(clamp8(r), clamp8(g), clamp8(b))
+	if i := p.lookup[key]; i >= 0 {
+		return int(i)
+	}
+
+	// Match the center of the 15 bit cell, so the table does not depend on
+	// which colour of the cell was looked up first
+	cr, cg, cb := unquant(key)
+	best, bestDist := 0, int32(-1)
+	for i, c := range p.colors {
+		dr, dg, db := int32(c.R)-cr, int32(c.G)-cg, int32(c.B)-cb
+		dist := dr*dr + dg*dg + db*db
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	p.lookup[key] = int16(best)
+	return best
+}
+
+// bucket is a 15 bit colour, how many pixels have it and the sum of their
+// exact colours
+type bucket struct {
+	key     int
+	count   int
+	r, g, b int
+}
+
+// medianCut picks up to n colours for img by repeatedly splitting the box of
+// colours with the widest channel at its median pixel. The result only
+// depends on the pixels, so the same image always gets the same palette.
+func medianCut(img *image.NRGBA, n int) *palette {
+	buckets := make([]bucket, quantLevels*quantLevels*quantLevels)
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.NRGBAAt(x, y)
+			if c.A < alphaThreshold {
+				continue
+			}
+			b := &buckets[quantKey(c.R, c.G, c.B)]
+			b.count++
+			b.r += int(c.R)
+			b.g += int(c.G)
+			b.b += int(c.B)
+		}
+	}
+
+	var all []bucket
+	for key, b := range buckets {
+		if b.count > 0 {
+			b.key = key
+			all = append(all, b)
+		}
+	}
+	if len(all) == 0 {
+		return newPalette([]color.NRGBA{{A: 0xff}})
+	}
+
+	boxes := [][]bucket{all}
+	for len(boxes) < n {
+		// Split the box with the widest range, the first one on a tie
+		split, channel, widest := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			if c, width := widestChannel(box); width > widest {
+				split, channel, widest = i, c, width
+			}
+		}
+		if split < 0 {
+			break
+		}
+
+		box := boxes[split]
+		sort.Slice(box, func(i, j int) bool {
+			a, b := channelOf(box[i].key, channel), channelOf(box[j].key, channel)
+			if a != b {
+				return a < b
+			}
+			return box[i].key < box[j].key
+		})
+
+		total := 0
+		for _, b := range box {
+			total += b.count
+		}
+		median, seen := 1, 0
+		for i, b := range box[:len(box)-1] {
+			seen += b.count
+			if seen*2 >= total {
+				median = i + 1
+				break
+			}
+		}
+
+		boxes[split] = box[:median:median]
+		boxes = append(boxes, box[median:])
+	}
+
+	colors := make([]color.NRGBA, len(boxes))
+	for i, box := range boxes {
+		colors[i] = meanColor(box)
+	}
+	return newPalette(colors)
+}
+
+// widestChannel returns the channel with the widest range of values in a
+// box and that range
+func widestChannel(box []bucket) (int, int) {
+	channel, widest := 0, 0
+	for c := 0; c < 3; c++ {
+		lo, hi := quantLevels, -1
+		for _, b := range box {
+			v := channelOf(b.key, c)
+			lo, hi = min(lo, v), max(hi, v)
+		}
+		if hi-lo > widest {
+			channel, widest = c, hi-lo
+		}
+	}
+	return channel, widest
+}
+
+// meanColor returns the average colour of the pixels in a box
+func meanColor(box []bucket) color.NRGBA {
+	var r, g, b, n int
+	for _, bk := range box {
+		r += bk.r
+		g += bk.g
+		b += bk.b
+		n += bk.count
+	}
+	return color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: 0xff}
+}
+
+// dither maps every pixel of img to a palette index with Floyd–Steinberg
+// error diffusion, row by row from the left. Transparent pixels are -1 and
+// take no part in the diffusion.
+func dither(img *image.NRGBA, p *palette) []int {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	indexes := make([]int, width*height)
+
+	// Errors are kept in sixteenths for this row and the next, with a spare
+	// entry on either side so the edges need no checks
+	cur := make([][3]int32, width+2)
+	next := make([][3]int32, width+2)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := img.NRGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
+			if c.A < alphaThreshold {
+				indexes[y*width+x] = -1
+				continue
+			}
+
+			e := cur[x+1]
+			r := clamp(int32(c.R) + e[0]/16)
+			g := clamp(int32(c.G) + e[1]/16)
+			b := clamp(int32(c.B) + e[2]/16)
+			i := p.nearest(r, g, b)
+			indexes[y*width+x] = i
+
+			chosen := p.colors[i]
+			diff := [3]int32{r - int32(chosen.R), g - int32(chosen.G), b - int32(chosen.B)}
+			for ch, d := range diff {
+				cur[x+2][ch] += d * 7
+				next[x][ch] += d * 3
+				next[x+1][ch] += d * 5
+				next[x+2][ch] += d
+			}
+		}
+		cur, next = next, cur
+		clear(next)
+	}
+	return indexes
+}
+
+// toNRGBA returns img as an NRGBA image, copying it only if needed
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok {
+		return nrgba
+	}
+	dst := image.NewNRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Src)
+	return dst
+}
+
+func quantKey(r, g, b uint8) int {
+	shift := 8 - quantBits
+	return int(r>>shift)<<(2*quantBits) | int(g>>shift)<<quantBits | int(b>>shift)
+}
+
+// unquant returns the center colour of a 15 bit cell
+func unquant(key int) (int32, int32, int32) {
+	shift := 8 - quantBits
+	half := int32(1) << (shift - 1)
+	r := int32(channelOf(key, 0))<<shift | half
+	g := int32(channelOf(key, 1))<<shift | half
+	b := int32(channelOf(key, 2))<<shift | half
+	return r, g, b
+}
+
+func channelOf(key, channel int) int {
+	return key >> ((2 - channel) * quantBits) & (quantLevels - 1)
+}
+
+func clamp(v int32) int32 {
+	return min(max(v, 0), 0xff)
+}
+
+func clamp8(v int32) uint8 {
+	return uint8(clamp(v))
+}
@@ -0,0 +1,101 @@
+package graphics
+
+import (
+	"image/color"
+	"slices"
+	"testing"
+)
+
+func TestMedianCutExactColours(t *testing.T) {
+	colours := []color.NRGBA{
+		{R: 0xff, A: 0xff},
+		{G: 0xff, A: 0xff},
+		{B: 0xff, A: 0xff},
+		{R: 0x12, G: 0x34, B: 0x56, A: 0xff},
+	}
+	img := fill(4, 3, func(x, y int) color.NRGBA {
+		if y == 2 && x == 3 {
+			// Transparent pixels take no part in the palette
+			return color.NRGBA{R: 0x99, G: 0x99, B: 0x99, A: 0x10}
+		}
+		return colours[x]
+	})
+
+	p := medianCut(img, 16)
+	if len(p.colors) != len(colours) {
+		t.Fatalf("medianCut() gave %d colours, want %d: %v", len(p.colors), len(colours), p.colors)
+	}
+	for _, c := range colours {
+		if !slices.Contains(p.colors, c) {
+			t.Errorf("medianCut() = %v, missing %v", p.colors, c)
+		}
+	}
+}
+
+func TestMedianCutLimit(t *testing.T) {
+	img := fill(64, 64, func(x, y int) color.NRGBA {
+		return color.NRGBA{R: uint8(x * 4), G: uint8(y * 4), B: uint8((x ^ y) * 4), A: 0xff}
+	})
+
+	for _, n := range []int{1, 2, 7, 256} {
+		if got := len(medianCut(img, n).colors); got != n {
+			t.Errorf("medianCut(n = %d) gave %d colours", n, got)
+		}
+	}
+}
+
+func TestMedianCutDeterministic(t *testing.T) {
+	at := func(x, y int) color.NRGBA {
+		return color.NRGBA{R: uint8(x * 9), G: uint8(y * 13), B: uint8(x * y), A: 0xff}
+	}
+	img := fill(20, 20, at)
+	want := medianCut(img, 16).colors
+
+	if got := medianCut(img, 16).colors; !slices.Equal(got, want) {
+		t.Errorf("medianCut() = %v on the second run, want %v", got, want)
+	}
+
+	// The palette depends on which pixels there are, not where they are
+	flipped := fill(20, 20, func(x, y int) color.NRGBA {
+		return at(19-x, 19-y)
+	})
+	if got := medianCut(flipped, 16).colors; !slices.Equal(got, want) {
+		t.Errorf("medianCut() = %v for the flipped image, want %v", got, want)
+	}
+}
+
+func TestMedianCutTransparent(t *testing.T) {
+	img := fill(3, 3, func(x, y int) color.NRGBA {
+		return color.NRGBA{R: 0xff, A: 0x7f}
+	})
+
+	p := medianCut(img, 16)
+	if want := []color.NRGBA{{A: 0xff}}; !slices.Equal(p.colors, want) {
+		t.Errorf("medianCut() = %v, want %v", p.colors, want)
+	}
+	for i, index := range dither(img, p) {
+		if index != -1 {
+			t.Errorf("dither()[%d] = %d, want -1", i, index)
+		}
+	}
+}
+
+func TestDitherExactColours(t *testing.T) {
+	img := fill(6, 4, func(x, y int) color.NRGBA {
+		if (x+y)%2 == 0 {
+			return color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+		}
+		return color.NRGBA{A: 0xff}
+	})
+
+	// Colours in the palette map to themselves and leave no error behind
+	p := medianCut(img, 2)
+	indexes := dither(img, p)
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 6; x++ {
+			if got, want := p.colors[indexes[y*6+x]], img.NRGBAAt(x, y); got != want {
+				t.Errorf("pixel (%d, %d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
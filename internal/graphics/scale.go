@@ -0,0 +1,99 @@
+package graphics
+
+import (
+	"image"
+	"image/color"
+)
+
+// Fit returns the size of an image of the given bounds scaled to fit
+// within width by height pixels, keeping its aspect ratio
+func Fit(bounds image.Rectangle, width, height int) image.Point {
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= 0 || srcH <= 0 || width <= 0 || height <= 0 {
+		return image.Point{}
+	}
+
+	// Compare width/srcW with height/srcH without dividing
+	if width*srcH <= height*srcW {
+		return image.Point{X: width, Y: max(1, srcH*width/srcW)}
+	}
+	return image.Point{X: max(1, srcW*height/srcH), Y: height}
+}
+
+// Resize scales img to width by height pixels. Shrinking averages every
+// source pixel covered by a target pixel, so fine lines and text survive.
+func Resize(img image.Image, width, height int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= 0 || srcH <= 0 || width <= 0 || height <= 0 {
+		return dst
+	}
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*srcH/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*srcH/height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*srcW/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*srcW/width)
+			dst.SetNRGBA(x, y, average(img, x0, y0, x1, y1))
+		}
+	}
+	return dst
+}
+
+// average returns the mean colour of the pixels in [x0,x1) x [y0,y1).
+// Decoded pages are NRGBA or YCbCr, whose pixels are read directly instead
+// of through At, which allocates a colour per pixel.
+func average(img image.Image, x0, y0, x1, y1 int) color.NRGBA {
+	var sum pixelSum
+	switch src := img.(type) {
+	case *image.NRGBA:
+		for y := y0; y < y1; y++ {
+			row := src.Pix[src.PixOffset(x0, y):src.PixOffset(x1, y)]
+			for i := 0; i < len(row); i += 4 {
+				sum.add(color.NRGBA{R: row[i], G: row[i+1], B: row[i+2], A: row[i+3]}.RGBA())
+			}
+		}
+	case *image.YCbCr:
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				yi, ci := src.YOffset(x, y), src.COffset(x, y)
+				sum.add(color.YCbCr{Y: src.Y[yi], Cb: src.Cb[ci], Cr: src.Cr[ci]}.RGBA())
+			}
+		}
+	default:
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				sum.add(img.At(x, y).RGBA())
+			}
+		}
+	}
+	return sum.mean()
+}
+
+// pixelSum adds up premultiplied 16 bit colours
+type pixelSum struct {
+	r, g, b, a, n uint64
+}
+
+func (s *pixelSum) add(r, g, b, a uint32) {
+	s.r += uint64(r)
+	s.g += uint64(g)
+	s.b += uint64(b)
+	s.a += uint64(a)
+	s.n++
+}
+
+// mean returns the average colour, no longer premultiplied
+func (s *pixelSum) mean() color.NRGBA {
+	if s.a == 0 {
+		return color.NRGBA{}
+	}
+	return color.NRGBA{
+		R: uint8(s.r * 0xff / s.a),
+		G: uint8(s.g * 0xff / s.a),
+		B: uint8(s.b * 0xff / s.a),
+		A: uint8(s.a / s.n >> 8),
+	}
+}
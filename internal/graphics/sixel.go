@@ -0,0 +1,109 @@
+package graphics
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"slices"
+)
+
+// sixelColors is the size of the palette most sixel terminals support
+const sixelColors = 256
+
+// sixelEncoder draws images as sixels, six rows of pixels per character,
+// with a median cut palette and Floyd–Steinberg dithering. Sixels replace
+// the cells they cover, so redrawing those cells removes the image.
+type sixelEncoder struct{}
+
+func (sixelEncoder) Protocol() Protocol {
+	return Sixel
+}
+
+func (sixelEncoder) Encode(w io.Writer, img image.Image, id uint32, cols, rows int) error {
+	src := toNRGBA(img)
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	p := medianCut(src, sixelColors)
+	indexes := dither(src, p)
+
+	out := bufio.NewWriter(w)
+
+	// P2=1 leaves transparent pixels alone, and the raster attributes give
+	// the size so terminals do not pad the last band
+	fmt.Fprintf(out, "\x1bP0;1;0q\"1;1;%d;%d", width, height)
+	for i, c := range p.colors {
+		fmt.Fprintf(out, "#%d;2;%d;%d;%d", i, percent(c.R), percent(c.G), percent(c.B))
+	}
+
+	// bits holds the sixels of every colour in the current band
+	bits := make([][]byte, len(p.colors))
+	inBand := make([]bool, len(p.colors))
+	for top := 0; top < height; top += 6 {
+		var used []int
+		for row := top; row < min(top+6, height); row++ {
+			for x := 0; x < width; x++ {
+				i := indexes[row*width+x]
+				if i < 0 {
+					continue
+				}
+				if bits[i] == nil {
+					bits[i] = make([]byte, width)
+				}
+				if !inBand[i] {
+					inBand[i] = true
+					used = append(used, i)
+				}
+				bits[i][x] |= 1 << (row - top)
+			}
+		}
+
+		// Colours go in palette order, returning to the start of the band
+		// before each but the first
+		slices.Sort(used)
+		for n, i := range used {
+			if n > 0 {
+				out.WriteByte('$')
+			}
+			fmt.Fprintf(out, "#%d", i)
+			writeSixels(out, bits[i])
+			clear(bits[i])
+			inBand[i] = false
+		}
+		out.WriteByte('-')
+	}
+	out.WriteString("\x1b\\")
+	return out.Flush()
+}
+
+func (sixelEncoder) Delete(w io.Writer, id uint32) error {
+	return nil
+}
+
+// writeSixels writes one colour of a band, run length encoding repeats and
+// leaving out the blank tail
+func writeSixels(out *bufio.Writer, bits []byte) {
+	end := len(bits)
+	for end > 0 && bits[end-1] == 0 {
+		end--
+	}
+	for x := 0; x < end; {
+		run := 1
+		for x+run < end && bits[x+run] == bits[x] {
+			run++
+		}
+		ch := '?' + bits[x]
+		if run > 3 {
+			fmt.Fprintf(out, "!%d%c", run, ch)
+		} else {
+			for n := 0; n < run; n++ {
+				out.WriteByte(ch)
+			}
+		}
+		x += run
+	}
+}
+
+// percent converts a colour channel to the 0-100 scale sixel uses
+func percent(v uint8) int {
+	return (int(v)*100 + 0x7f) / 0xff
+}
@@ -0,0 +1,113 @@
+package graphics
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestSixelEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image.Image
+	}{
+		{
+			// 8 rows make a full band and a short one
+			name: "two_colours",
+			img: fill(4, 8, func(x, y int) color.NRGBA {
+				if x < 2 {
+					return color.NRGBA{R: 0xff, A: 0xff}
+				}
+				return color.NRGBA{B: 0xff, A: 0xff}
+			}),
+		},
+		{
+			// Transparent pixels leave gaps in the bands of every colour
+			name: "transparent",
+			img: fill(5, 7, func(x, y int) color.NRGBA {
+				switch {
+				case (x+y)%3 == 0:
+					return color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x7f}
+				case y < 3:
+					return color.NRGBA{G: 0x80, A: 0xff}
+				default:
+					return color.NRGBA{R: 0x20, G: 0x20, B: 0x20, A: 0xff}
+				}
+			}),
+		},
+		{
+			// Wide runs are run length encoded, the last band has one row
+			name: "runs",
+			img: fill(12, 13, func(x, y int) color.NRGBA {
+				if y == 12 && x >= 8 {
+					return color.NRGBA{}
+				}
+				return color.NRGBA{R: 0xff, G: 0xff, A: 0xff}
+			}),
+		},
+		{
+			// More colours than fit the palette, so dithering kicks in
+			name: "gradient",
+			img: fill(40, 10, func(x, y int) color.NRGBA {
+				return color.NRGBA{R: uint8(x * 6), G: uint8(y * 25), B: uint8((x + y) * 5), A: 0xff}
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bytes.Buffer
+			if err := (sixelEncoder{}).Encode(&got, tt.img, 1, 1, 1); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+
+			golden := filepath.Join("testdata", tt.name+".sixel")
+			if *update {
+				if err := os.WriteFile(golden, got.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("Encode() =\n%q\nwant\n%q", got.Bytes(), want)
+			}
+		})
+	}
+}
+
+func TestSixelEncodeDeterministic(t *testing.T) {
+	img := fill(30, 17, func(x, y int) color.NRGBA {
+		return color.NRGBA{R: uint8(x * 8), G: uint8(y * 15), B: uint8(x * y), A: 0xff}
+	})
+
+	var first, second bytes.Buffer
+	if err := (sixelEncoder{}).Encode(&first, img, 1, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := (sixelEncoder{}).Encode(&second, img, 2, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("Encode() gave different output for the same image")
+	}
+}
+
+// fill returns an image of the given size with the colour of each pixel
+func fill(width, height int, at func(x, y int) color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, at(x, y))
+		}
+	}
+	return img
+}
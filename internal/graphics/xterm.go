@@ -0,0 +1,57 @@
+package graphics
+
+import (
+	"image"
+	"image/color"
+	"sync"
+)
+
+// xtermFirst is the first xterm colour used. The 16 below it change with
+// the theme, so only the colour cube and grey ramp are safe.
+const xtermFirst = 16
+
+var (
+	xtermOnce    sync.Once
+	xtermPalette *palette
+)
+
+// xterm returns the palette of xterm colours 16 to 255, with its lookup
+// table filled so it can be shared
+func xterm() *palette {
+	xtermOnce.Do(func() {
+		levels := []uint8{0, 95, 135, 175, 215, 255}
+		var colors []color.NRGBA
+		for _, r := range levels {
+			for _, g := range levels {
+				for _, b := range levels {
+					colors = append(colors, color.NRGBA{R: r, G: g, B: b, A: 0xff})
+				}
+			}
+		}
+		for i := 0; i < 24; i++ {
+			v := uint8(8 + 10*i)
+			colors = append(colors, color.NRGBA{R: v, G: v, B: v, A: 0xff})
+		}
+
+		xtermPalette = newPalette(colors)
+		for key := range xtermPalette.lookup {
+			r, g, b := unquant(key)
+			xtermPalette.nearest(r, g, b)
+		}
+	})
+	return xtermPalette
+}
+
+// DitherXterm256 reduces img to xterm's 256 colours with Floyd–Steinberg
+// dithering and returns the colour number of every pixel, row by row, or -1
+// for transparent ones. Spreading the error of each pixel over its
+// neighbours trades banding for grain.
+func DitherXterm256(img *image.NRGBA) []int {
+	indexes := dither(img, xterm())
+	for i, index := range indexes {
+		if index >= 0 {
+			indexes[i] = index + xtermFirst
+		}
+	}
+	return indexes
+}
@@ -0,0 +1,266 @@
+package library
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+	"github.com/sangnt1552314/mangadex-tui/internal/storage"
+)
+
+const libraryFileName = "library.json"
+
+const (
+	CategoryReading    = "Reading"
+	CategoryPlanToRead = "Plan to read"
+	CategoryCompleted  = "Completed"
+)
+
+// DefaultCategories are always present and cannot be removed
+var DefaultCategories = []string{CategoryReading, CategoryPlanToRead, CategoryCompleted}
+
+// SortBy selects the order of Entries
+type SortBy int
+
+const (
+	SortByTitle SortBy = iota
+	SortByRecentlyAdded
+	SortByRecentlyRead
+	SortByUnread
+)
+
+// Entry is a manga saved to the library. Manga is a snapshot of the
+// metadata taken when the entry was added or last refreshed.
+type Entry struct {
+	Manga           models.Manga `json:"manga"`
+	Category        string       `json:"category"`
+	AddedAt         time.Time    `json:"addedAt"`
+	ChapterCount    int          `json:"chapterCount"`
+	ReadCount       int          `json:"readCount"`
+	LastReadChapter string       `json:"lastReadChapter"`
+	LastReadAt      time.Time    `json:"lastReadAt"`
+}
+
+// Title returns the display title of the entry
+func (e Entry) Title() string {
+	return e.Manga.Title()
+}
+
+// Unread returns the number of chapters not read yet
+func (e Entry) Unread() int {
+	if unread := e.ChapterCount - e.ReadCount; unread > 0 {
+		return unread
+	}
+	return 0
+}
+
+// Filter narrows down Entries. Empty fields match everything.
+type Filter struct {
+	Category string
+	Query    string
+	SortBy   SortBy
+}
+
+type libraryFile struct {
+	Categories []string          `json:"categories"`
+	Entries    map[string]*Entry `json:"entries"`
+}
+
+// Library is the local collection of saved manga, persisted as JSON in
+// the user data directory.
+type Library struct {
+	mu   sync.Mutex
+	path string
+	data libraryFile
+}
+
+// Open loads the library from dir, starting empty if none exists yet
+func Open(dir string) (*Library, error) {
+	l := &Library{
+		path: filepath.Join(dir, libraryFileName),
+		data: libraryFile{Entries: make(map[string]*Entry)},
+	}
+
+	if _, err := storage.ReadJSON(l.path, &l.data); err != nil {
+		return nil, err
+	}
+	if l.data.Entries == nil {
+		l.data.Entries = make(map[string]*Entry)
+	}
+
+	return l, nil
+}
+
+// Categories returns the default categories followed by custom ones
+func (l *Library) Categories() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	categories := append([]string{}, DefaultCategories...)
+	return append(categories, l.data.Categories...)
+}
+
+// AddCategory creates a custom category
+func (l *Library) AddCategory(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("category name is empty")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.hasCategory(name) {
+		return fmt.Errorf("category %q already exists", name)
+	}
+	l.data.Categories = append(l.data.Categories, name)
+
+	return l.save()
+}
+
+// RemoveCategory deletes a custom category, moving its entries to Plan to read
+func (l *Library) RemoveCategory(name string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, category := range l.data.Categories {
+		if category == name {
+			l.data.Categories = append(l.data.Categories[:i], l.data.Categories[i+1:]...)
+			for _, entry := range l.data.Entries {
+				if entry.Category == name {
+					entry.Category = CategoryPlanToRead
+				}
+			}
+			return l.save()
+		}
+	}
+
+	return fmt.Errorf("category %q cannot be removed", name)
+}
+
+// Add saves manga under category, or moves it there if already saved
+func (l *Library) Add(manga models.Manga, category string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.hasCategory(category) {
+		return fmt.Errorf("unknown category %q", category)
+	}
+
+	if entry, ok := l.data.Entries[manga.ID]; ok {
+		entry.Manga = manga
+		entry.Category = category
+	} else {
+		l.data.Entries[manga.ID] = &Entry{
+			Manga:    manga,
+			Category: category,
+			AddedAt:  time.Now(),
+		}
+	}
+
+	return l.save()
+}
+
+// Remove deletes a manga from the library
+func (l *Library) Remove(mangaID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.data.Entries[mangaID]; !ok {
+		return nil
+	}
+	delete(l.data.Entries, mangaID)
+
+	return l.save()
+}
+
+// Get returns the entry for a manga
+func (l *Library) Get(mangaID string) (Entry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.data.Entries[mangaID]
+	if !ok {
+		return Entry{}, false
+	}
+	return *entry, true
+}
+
+// Update changes a saved entry in place. It does nothing if the manga is
+// not in the library.
+func (l *Library) Update(mangaID string, fn func(entry *Entry)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.data.Entries[mangaID]
+	if !ok {
+		return nil
+	}
+	fn(entry)
+
+	return l.save()
+}
+
+// Entries returns the entries matching filter in the requested order
+func (l *Library) Entries(filter Filter) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	query := strings.ToLower(strings.TrimSpace(filter.Query))
+
+	var entries []Entry
+	for _, entry := range l.data.Entries {
+		if filter.Category != "" && entry.Category != filter.Category {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(entry.Title()), query) {
+			continue
+		}
+		entries = append(entries, *entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch filter.SortBy {
+		case SortByRecentlyAdded:
+			if !a.AddedAt.Equal(b.AddedAt) {
+				return a.AddedAt.After(b.AddedAt)
+			}
+		case SortByRecentlyRead:
+			if !a.LastReadAt.Equal(b.LastReadAt) {
+				return a.LastReadAt.After(b.LastReadAt)
+			}
+		case SortByUnread:
+			if a.Unread() != b.Unread() {
+				return a.Unread() > b.Unread()
+			}
+		}
+		return strings.ToLower(a.Title()) < strings.ToLower(b.Title())
+	})
+
+	return entries
+}
+
+// hasCategory reports whether name is a known category; the caller holds the lock
+func (l *Library) hasCategory(name string) bool {
+	for _, category := range DefaultCategories {
+		if category == name {
+			return true
+		}
+	}
+	for _, category := range l.data.Categories {
+		if category == name {
+			return true
+		}
+	}
+	return false
+}
+
+// save writes the library to disk; the caller holds the lock
+func (l *Library) save() error {
+	return storage.WriteJSON(l.path, l.data, 0600)
+}
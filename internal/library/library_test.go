@@ -0,0 +1,201 @@
+package library
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+)
+
+func openTest(t *testing.T, dir string) *Library {
+	t.Helper()
+	l, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return l
+}
+
+func testManga(id, title string) models.Manga {
+	var manga models.Manga
+	manga.ID = id
+	manga.Attributes.Title = map[string]string{"en": title}
+	return manga
+}
+
+func add(t *testing.T, l *Library, id, title, category string) {
+	t.Helper()
+	if err := l.Add(testManga(id, title), category); err != nil {
+		t.Fatalf("Add(%q) error = %v", id, err)
+	}
+}
+
+// titles lists the titles of the entries matching filter in order
+func titles(l *Library, filter Filter) string {
+	var names []string
+	for _, entry := range l.Entries(filter) {
+		names = append(names, entry.Title())
+	}
+	return strings.Join(names, ", ")
+}
+
+func TestAddAndRemove(t *testing.T) {
+	l := openTest(t, t.TempDir())
+
+	add(t, l, "m1", "Berserk", CategoryReading)
+	entry, ok := l.Get("m1")
+	if !ok {
+		t.Fatal("Get() found nothing after Add()")
+	}
+	if entry.Category != CategoryReading || entry.AddedAt.IsZero() {
+		t.Errorf("Get() = %+v", entry)
+	}
+
+	// Adding again moves the entry and keeps when it was first added
+	add(t, l, "m1", "Berserk Deluxe", CategoryCompleted)
+	moved, _ := l.Get("m1")
+	if moved.Category != CategoryCompleted || moved.Title() != "Berserk Deluxe" || !moved.AddedAt.Equal(entry.AddedAt) {
+		t.Errorf("Get() after adding again = %+v", moved)
+	}
+
+	if err := l.Add(testManga("m2", "Monster"), "Dropped"); err == nil {
+		t.Error("Add() to an unknown category error = nil")
+	}
+
+	if err := l.Update("m1", func(entry *Entry) { entry.ReadCount = 3 }); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if entry, _ := l.Get("m1"); entry.ReadCount != 3 {
+		t.Errorf("ReadCount = %d after Update(), want 3", entry.ReadCount)
+	}
+	if err := l.Update("missing", func(entry *Entry) { t.Error("Update() called fn for a missing manga") }); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	if err := l.Remove("m1"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, ok := l.Get("m1"); ok {
+		t.Error("Get() found the entry after Remove()")
+	}
+	if err := l.Remove("m1"); err != nil {
+		t.Errorf("Remove() of a missing manga error = %v", err)
+	}
+}
+
+func TestCategories(t *testing.T) {
+	l := openTest(t, t.TempDir())
+
+	if err := l.AddCategory("  On hold "); err != nil {
+		t.Fatalf("AddCategory() error = %v", err)
+	}
+	want := strings.Join(append(append([]string{}, DefaultCategories...), "On hold"), ", ")
+	if got := strings.Join(l.Categories(), ", "); got != want {
+		t.Errorf("Categories() = %s, want %s", got, want)
+	}
+
+	tests := []struct {
+		name     string
+		category string
+	}{
+		{"empty", "   "},
+		{"duplicate", "On hold"},
+		{"default", CategoryReading},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := l.AddCategory(tt.category); err == nil {
+				t.Errorf("AddCategory(%q) error = nil", tt.category)
+			}
+		})
+	}
+
+	add(t, l, "m1", "Berserk", "On hold")
+	add(t, l, "m2", "Monster", CategoryReading)
+
+	if err := l.RemoveCategory(CategoryReading); err == nil {
+		t.Error("RemoveCategory() of a default category error = nil")
+	}
+	if err := l.RemoveCategory("On hold"); err != nil {
+		t.Fatalf("RemoveCategory() error = %v", err)
+	}
+	if got := strings.Join(l.Categories(), ", "); got != strings.Join(DefaultCategories, ", ") {
+		t.Errorf("Categories() after removing = %s", got)
+	}
+	if entry, _ := l.Get("m1"); entry.Category != CategoryPlanToRead {
+		t.Errorf("entry of a removed category moved to %q, want %q", entry.Category, CategoryPlanToRead)
+	}
+	if entry, _ := l.Get("m2"); entry.Category != CategoryReading {
+		t.Errorf("entry of another category moved to %q", entry.Category)
+	}
+}
+
+func TestEntries(t *testing.T) {
+	l := openTest(t, t.TempDir())
+	add(t, l, "m1", "Monster", CategoryReading)
+	add(t, l, "m2", "berserk", CategoryReading)
+	add(t, l, "m3", "Vagabond", CategoryCompleted)
+
+	now := time.Now()
+	updates := map[string]Entry{
+		"m1": {AddedAt: now.Add(-2 * time.Hour), LastReadAt: now, ChapterCount: 10, ReadCount: 9},
+		"m2": {AddedAt: now.Add(-time.Hour), LastReadAt: now.Add(-time.Hour), ChapterCount: 10},
+		"m3": {AddedAt: now, ChapterCount: 5, ReadCount: 2},
+	}
+	for id, update := range updates {
+		err := l.Update(id, func(entry *Entry) {
+			entry.AddedAt = update.AddedAt
+			entry.LastReadAt = update.LastReadAt
+			entry.ChapterCount = update.ChapterCount
+			entry.ReadCount = update.ReadCount
+		})
+		if err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   string
+	}{
+		{"by title", Filter{}, "berserk, Monster, Vagabond"},
+		{"category", Filter{Category: CategoryReading}, "berserk, Monster"},
+		{"query", Filter{Query: " BOND "}, "Vagabond"},
+		{"recently added", Filter{SortBy: SortByRecentlyAdded}, "Vagabond, berserk, Monster"},
+		{"recently read", Filter{SortBy: SortByRecentlyRead}, "Monster, berserk, Vagabond"},
+		{"unread", Filter{SortBy: SortByUnread}, "berserk, Vagabond, Monster"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := titles(l, tt.filter); got != tt.want {
+				t.Errorf("Entries() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	l := openTest(t, dir)
+	if err := l.AddCategory("On hold"); err != nil {
+		t.Fatalf("AddCategory() error = %v", err)
+	}
+	add(t, l, "m1", "Berserk", "On hold")
+	add(t, l, "m2", "Monster", CategoryReading)
+	if err := l.Remove("m2"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+
+	reopened := openTest(t, dir)
+	if got := reopened.Categories(); got[len(got)-1] != "On hold" {
+		t.Errorf("Categories() after reopening = %v", got)
+	}
+	if entry, ok := reopened.Get("m1"); !ok || entry.Category != "On hold" || entry.Title() != "Berserk" {
+		t.Errorf("Get() after reopening = %+v, %t", entry, ok)
+	}
+	if _, ok := reopened.Get("m2"); ok {
+		t.Error("removed entry is back after reopening")
+	}
+}
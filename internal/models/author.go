@@ -0,0 +1,24 @@
+package models
+
+type Author struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Attributes struct {
+		Name      string            `json:"name"`
+		ImageUrl  *string           `json:"imageUrl"`
+		Biography map[string]string `json:"biography"`
+		CreatedAt string            `json:"createdAt"`
+		UpdatedAt string            `json:"updatedAt"`
+		Version   int               `json:"version"`
+	} `json:"attributes"`
+	Relationships []Relationship `json:"relationships"`
+}
+
+type AuthorListResponse struct {
+	Result   string   `json:"result"`
+	Response string   `json:"response"`
+	Data     []Author `json:"data"`
+	Limit    int      `json:"limit"`
+	Offset   int      `json:"offset"`
+	Total    int      `json:"total"`
+}
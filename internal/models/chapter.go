@@ -3,12 +3,30 @@ package models
 import "time"
 
 type ChapterQueryParams struct {
-	Limit              int               `json:"limit"`
-	Offset             int               `json:"offset"`
-	Ids                []string          `json:"ids"`
-	MangaId            string            `json:"manga"`
-	TranslatedLanguage []string          `json:"translatedLanguage"`
-	Order              map[string]string `json:"order"`
+	Limit                    int       `json:"limit"`
+	Offset                   int       `json:"offset"`
+	Ids                      []string  `json:"ids"`
+	Title                    string    `json:"title"`
+	Groups                   []string  `json:"groups"`
+	Uploader                 []string  `json:"uploader"`
+	MangaId                  string    `json:"manga"`
+	Volume                   []string  `json:"volume"`
+	Chapter                  []string  `json:"chapter"`
+	TranslatedLanguage       []string  `json:"translatedLanguage"`
+	OriginalLanguage         []string  `json:"originalLanguage"`
+	ExcludedOriginalLanguage []string  `json:"excludedOriginalLanguage"`
+	ContentRating            []string  `json:"contentRating"`
+	ExcludedGroups           []string  `json:"excludedGroups"`
+	ExcludedUploaders        []string  `json:"excludedUploaders"`
+	IncludeFutureUpdates     *bool     `json:"includeFutureUpdates"`
+	IncludeEmptyPages        *bool     `json:"includeEmptyPages"`
+	IncludeFuturePublishAt   *bool     `json:"includeFuturePublishAt"`
+	IncludeExternalUrl       *bool     `json:"includeExternalUrl"`
+	CreatedAtSince           time.Time `json:"createdAtSince"`
+	UpdatedAtSince           time.Time `json:"updatedAtSince"`
+	PublishAtSince           time.Time `json:"publishAtSince"`
+	Order                    []Order   `json:"order"`
+	Includes                 []string  `json:"includes"`
 }
 
 type Chapter struct {
@@ -34,8 +52,13 @@ type ChapterAttributes struct {
 }
 
 type ChapterRelationship struct {
-	ID   string `json:"id"`
-	Type string `json:"type"`
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Attributes struct {
+		// Set for scanlation groups and users when included
+		Name     string `json:"name"`
+		Username string `json:"username"`
+	} `json:"attributes"`
 }
 
 type ChapterListResponse struct {
@@ -1,5 +1,7 @@
 package models
 
+import "fmt"
+
 type ImageResponse struct {
 	Result  string `json:"result"`
 	BaseURL string `json:"baseUrl"`
@@ -9,3 +11,28 @@ type ImageResponse struct {
 		DataSaver []string `json:"dataSaver"`
 	} `json:"chapter"`
 }
+
+// PageURLs returns the URLs of the chapter pages on the MangaDex@Home node,
+// using the compressed data-saver images when dataSaver is set
+func (r *ImageResponse) PageURLs(dataSaver bool) []string {
+	files, quality := r.Chapter.Data, "data"
+	if dataSaver && len(r.Chapter.DataSaver) > 0 {
+		files, quality = r.Chapter.DataSaver, "data-saver"
+	}
+
+	urls := make([]string, len(files))
+	for i, file := range files {
+		urls[i] = fmt.Sprintf("%s/%s/%s/%s", r.BaseURL, quality, r.Chapter.Hash, file)
+	}
+	return urls
+}
+
+// AtHomeReport tells MangaDex@Home how fetching an image from a node went.
+// Duration is in milliseconds.
+type AtHomeReport struct {
+	URL      string `json:"url"`
+	Success  bool   `json:"success"`
+	Cached   bool   `json:"cached"`
+	Bytes    int    `json:"bytes"`
+	Duration int64  `json:"duration"`
+}
@@ -1,17 +1,57 @@
 package models
 
+import "time"
+
 const (
-	OrderByCreatedAt   = "createdAt"
-	OrderByRating      = "rating"
-	OrderByFollowCount = "followedCount"
+	OrderByCreatedAt             = "createdAt"
+	OrderByRating                = "rating"
+	OrderByFollowCount           = "followedCount"
+	OrderByTitle                 = "title"
+	OrderByYear                  = "year"
+	OrderByUpdatedAt             = "updatedAt"
+	OrderByLatestUploadedChapter = "latestUploadedChapter"
+	OrderByRelevance             = "relevance"
+	OrderByVolume                = "volume"
+	OrderByChapter               = "chapter"
+	OrderByPublishAt             = "publishAt"
+	OrderByReadableAt            = "readableAt"
+	OrderAsc                     = "asc"
+	OrderDesc                    = "desc"
+	TagsModeAnd                  = "AND"
+	TagsModeOr                   = "OR"
 )
 
+// Order is a single sort key. When several are given, earlier keys take precedence.
+type Order struct {
+	Field     string `json:"field"`
+	Direction string `json:"direction"`
+}
+
 type MangaQueryParams struct {
-	Limit         int               `json:"limit"`
-	ContentRating []string          `json:"contentRating"`
-	Order         map[string]string `json:"order"`
-	Includes      []string          `json:"includes"`
-	HasChapters   bool              `json:"hasAvailableChapters"`
+	Limit                       int       `json:"limit"`
+	Offset                      int       `json:"offset"`
+	Title                       string    `json:"title"`
+	AuthorOrArtist              string    `json:"authorOrArtist"`
+	Authors                     []string  `json:"authors"`
+	Artists                     []string  `json:"artists"`
+	Year                        int       `json:"year"`
+	IncludedTags                []string  `json:"includedTags"`
+	IncludedTagsMode            string    `json:"includedTagsMode"`
+	ExcludedTags                []string  `json:"excludedTags"`
+	ExcludedTagsMode            string    `json:"excludedTagsMode"`
+	Status                      []string  `json:"status"`
+	OriginalLanguage            []string  `json:"originalLanguage"`
+	ExcludedOriginalLanguage    []string  `json:"excludedOriginalLanguage"`
+	AvailableTranslatedLanguage []string  `json:"availableTranslatedLanguage"`
+	PublicationDemographic      []string  `json:"publicationDemographic"`
+	Ids                         []string  `json:"ids"`
+	ContentRating               []string  `json:"contentRating"`
+	CreatedAtSince              time.Time `json:"createdAtSince"`
+	UpdatedAtSince              time.Time `json:"updatedAtSince"`
+	Order                       []Order   `json:"order"`
+	Includes                    []string  `json:"includes"`
+	HasChapters                 bool      `json:"hasAvailableChapters"`
+	Group                       string    `json:"group"`
 }
 
 type Manga struct {
@@ -42,6 +82,35 @@ type Manga struct {
 	Relationships []MangaRelationship `json:"relationships"`
 }
 
+// Title returns the English title, falling back on an English or else the
+// first alternative title
+func (m Manga) Title() string {
+	title := m.Attributes.Title["en"]
+
+	var altTitle string
+	if len(m.Attributes.AltTitles) > 0 {
+		for _, title := range m.Attributes.AltTitles {
+			if engTitle, ok := title["en"]; ok {
+				altTitle = engTitle
+				break
+			}
+		}
+
+		if altTitle == "" && len(m.Attributes.AltTitles) > 0 {
+			for _, val := range m.Attributes.AltTitles[0] {
+				altTitle = val
+				break
+			}
+		}
+	}
+
+	if title == "" && altTitle != "" {
+		title = altTitle
+	}
+
+	return title
+}
+
 type Tag struct {
 	ID         string `json:"id"`
 	Type       string `json:"type"`
@@ -54,6 +123,15 @@ type Tag struct {
 	Relationships []Relationship `json:"relationships"`
 }
 
+type TagListResponse struct {
+	Result   string `json:"result"`
+	Response string `json:"response"`
+	Data     []Tag  `json:"data"`
+	Limit    int    `json:"limit"`
+	Offset   int    `json:"offset"`
+	Total    int    `json:"total"`
+}
+
 type MangaRelationship struct {
 	ID         string `json:"id"`
 	Type       string `json:"type"`
@@ -0,0 +1,19 @@
+package models
+
+// Page is one page of a paginated list response
+type Page[T any] struct {
+	Items  []T `json:"items"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Total  int `json:"total"`
+}
+
+// NextOffset returns the offset of the page following this one
+func (p *Page[T]) NextOffset() int {
+	return p.Offset + len(p.Items)
+}
+
+// HasMore reports whether there are results after this page
+func (p *Page[T]) HasMore() bool {
+	return len(p.Items) > 0 && p.NextOffset() < p.Total
+}
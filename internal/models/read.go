@@ -0,0 +1,19 @@
+package models
+
+// ReadMarkersResponse lists the read chapter IDs of one manga
+type ReadMarkersResponse struct {
+	Result string   `json:"result"`
+	Data   []string `json:"data"`
+}
+
+// GroupedReadMarkersResponse lists read chapter IDs keyed by manga ID
+type GroupedReadMarkersResponse struct {
+	Result string              `json:"result"`
+	Data   map[string][]string `json:"data"`
+}
+
+// ReadMarkersUpdate is the body of a read marker batch update
+type ReadMarkersUpdate struct {
+	ChapterIdsRead   []string `json:"chapterIdsRead"`
+	ChapterIdsUnread []string `json:"chapterIdsUnread"`
+}
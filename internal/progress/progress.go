@@ -0,0 +1,310 @@
+package progress
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+	"github.com/sangnt1552314/mangadex-tui/internal/storage"
+)
+
+const progressFileName = "progress.json"
+
+// ChapterProgress is how far a chapter has been read. Page is zero based.
+// Chapters marked read by a sync have only their ID set in Chapter and a
+// zero UpdatedAt.
+type ChapterProgress struct {
+	MangaID   string         `json:"mangaId"`
+	Chapter   models.Chapter `json:"chapter"`
+	Page      int            `json:"page"`
+	Pages     int            `json:"pages"`
+	Completed bool           `json:"completed"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+// ChapterID returns the ID of the chapter this progress belongs to
+func (c ChapterProgress) ChapterID() string {
+	return c.Chapter.ID
+}
+
+// HistoryEntry is a chapter in the reading history along with its manga
+type HistoryEntry struct {
+	ChapterProgress
+	Manga models.Manga
+}
+
+// Title returns the display title of the manga
+func (h HistoryEntry) Title() string {
+	return h.Manga.Title()
+}
+
+type progressFile struct {
+	Manga    map[string]models.Manga                `json:"manga"`
+	Chapters map[string]map[string]*ChapterProgress `json:"chapters"`
+}
+
+// Store keeps per-manga, per-chapter reading progress in the user data
+// directory.
+type Store struct {
+	mu   sync.Mutex
+	path string
+	data progressFile
+}
+
+// Open loads reading progress from dir, starting empty if none exists yet
+func Open(dir string) (*Store, error) {
+	s := &Store{
+		path: filepath.Join(dir, progressFileName),
+	}
+
+	if _, err := storage.ReadJSON(s.path, &s.data); err != nil {
+		return nil, err
+	}
+	if s.data.Manga == nil {
+		s.data.Manga = make(map[string]models.Manga)
+	}
+	if s.data.Chapters == nil {
+		s.data.Chapters = make(map[string]map[string]*ChapterProgress)
+	}
+
+	return s, nil
+}
+
+// Update records that page of pages has been reached in chapter. The
+// chapter is completed once its last page is shown and stays completed
+// when paging back.
+func (s *Store) Update(manga models.Manga, chapter models.Chapter, page, pages int) (ChapterProgress, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chapters, ok := s.data.Chapters[manga.ID]
+	if !ok {
+		chapters = make(map[string]*ChapterProgress)
+		s.data.Chapters[manga.ID] = chapters
+	}
+
+	progress, ok := chapters[chapter.ID]
+	if !ok {
+		progress = &ChapterProgress{MangaID: manga.ID}
+		chapters[chapter.ID] = progress
+	}
+
+	progress.Chapter = chapter
+	progress.Page = page
+	progress.Pages = pages
+	progress.Completed = progress.Completed || (pages > 0 && page >= pages-1)
+	progress.UpdatedAt = time.Now()
+	s.data.Manga[manga.ID] = manga
+
+	return *progress, s.save()
+}
+
+// Get returns the progress of a chapter
+func (s *Store) Get(mangaID, chapterID string) (ChapterProgress, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	progress, ok := s.data.Chapters[mangaID][chapterID]
+	if !ok {
+		return ChapterProgress{}, false
+	}
+	return *progress, true
+}
+
+// ForManga returns the progress of every chapter of a manga keyed by chapter ID
+func (s *Store) ForManga(mangaID string) map[string]ChapterProgress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make(map[string]ChapterProgress, len(s.data.Chapters[mangaID]))
+	for id, progress := range s.data.Chapters[mangaID] {
+		result[id] = *progress
+	}
+	return result
+}
+
+// Latest returns the most recently read chapter of a manga
+func (s *Store) Latest(mangaID string) (ChapterProgress, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var latest *ChapterProgress
+	for _, progress := range s.data.Chapters[mangaID] {
+		if progress.UpdatedAt.IsZero() {
+			continue
+		}
+		if latest == nil || progress.UpdatedAt.After(latest.UpdatedAt) {
+			latest = progress
+		}
+	}
+	if latest == nil {
+		return ChapterProgress{}, false
+	}
+	return *latest, true
+}
+
+// MarkRead completes chapters of a manga without touching their page or
+// read time. It returns how many chapters were not completed before.
+func (s *Store) MarkRead(manga models.Manga, chapterIDs []string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chapters, ok := s.data.Chapters[manga.ID]
+	if !ok {
+		chapters = make(map[string]*ChapterProgress)
+		s.data.Chapters[manga.ID] = chapters
+	}
+
+	changed := 0
+	for _, id := range chapterIDs {
+		progress, ok := chapters[id]
+		if !ok {
+			progress = &ChapterProgress{MangaID: manga.ID, Chapter: models.Chapter{ID: id}}
+			chapters[id] = progress
+		}
+		if !progress.Completed {
+			progress.Completed = true
+			changed++
+		}
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+	if _, ok := s.data.Manga[manga.ID]; !ok {
+		s.data.Manga[manga.ID] = manga
+	}
+
+	return changed, s.save()
+}
+
+// CompletedIDs returns the IDs of the completed chapters of a manga
+func (s *Store) CompletedIDs(mangaID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	for id, progress := range s.data.Chapters[mangaID] {
+		if progress.Completed {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Manga returns the manga progress has been recorded for, keyed by ID
+func (s *Store) Manga() map[string]models.Manga {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make(map[string]models.Manga, len(s.data.Manga))
+	for id, manga := range s.data.Manga {
+		result[id] = manga
+	}
+	return result
+}
+
+// ReadCount returns how many chapters of a manga are completed
+func (s *Store) ReadCount(mangaID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, progress := range s.data.Chapters[mangaID] {
+		if progress.Completed {
+			count++
+		}
+	}
+	return count
+}
+
+// History returns the chapters read in this app, most recent first. A
+// non-empty query keeps the chapters whose manga title, chapter number or
+// chapter title contain it.
+func (s *Store) History(query string) []HistoryEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	var entries []HistoryEntry
+	for mangaID, chapters := range s.data.Chapters {
+		for _, progress := range chapters {
+			if progress.UpdatedAt.IsZero() {
+				continue
+			}
+
+			entry := HistoryEntry{ChapterProgress: *progress, Manga: s.data.Manga[mangaID]}
+			if query != "" && !entry.matches(query) {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].UpdatedAt.Equal(entries[j].UpdatedAt) {
+			return entries[i].UpdatedAt.After(entries[j].UpdatedAt)
+		}
+		return entries[i].ChapterID() < entries[j].ChapterID()
+	})
+
+	return entries
+}
+
+// RemoveHistory drops a chapter from the reading history. Its saved page is
+// forgotten but a completed chapter stays completed, so read markers are
+// not lost.
+func (s *Store) RemoveHistory(mangaID, chapterID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	progress, ok := s.data.Chapters[mangaID][chapterID]
+	if !ok {
+		return nil
+	}
+	s.forget(mangaID, chapterID, progress)
+
+	return s.save()
+}
+
+// ClearHistory drops every chapter from the reading history, keeping
+// completed chapters completed like RemoveHistory
+func (s *Store) ClearHistory() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for mangaID, chapters := range s.data.Chapters {
+		for chapterID, progress := range chapters {
+			s.forget(mangaID, chapterID, progress)
+		}
+	}
+
+	return s.save()
+}
+
+// forget clears the read time and page of a chapter, deleting it if it was
+// not completed; the caller holds the lock
+func (s *Store) forget(mangaID, chapterID string, progress *ChapterProgress) {
+	if !progress.Completed {
+		delete(s.data.Chapters[mangaID], chapterID)
+		return
+	}
+	progress.Page = 0
+	progress.UpdatedAt = time.Time{}
+}
+
+func (h HistoryEntry) matches(query string) bool {
+	attributes := h.Chapter.Attributes
+	return strings.Contains(strings.ToLower(h.Title()), query) ||
+		strings.Contains(strings.ToLower(attributes.Chapter), query) ||
+		strings.Contains(strings.ToLower(attributes.Title), query)
+}
+
+// save writes the progress to disk; the caller holds the lock
+func (s *Store) save() error {
+	return storage.WriteJSON(s.path, s.data, 0600)
+}
@@ -0,0 +1,223 @@
+package progress
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+)
+
+func openTest(t *testing.T, dir string) *Store {
+	t.Helper()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return s
+}
+
+func testManga(id, title string) models.Manga {
+	var manga models.Manga
+	manga.ID = id
+	manga.Attributes.Title = map[string]string{"en": title}
+	return manga
+}
+
+func testChapter(id, number, title string) models.Chapter {
+	return models.Chapter{
+		ID:         id,
+		Attributes: models.ChapterAttributes{Chapter: number, Title: title},
+	}
+}
+
+// read records page of pages, leaving a moment between calls so every
+// update has its own time
+func read(t *testing.T, s *Store, manga models.Manga, chapter models.Chapter, page, pages int) ChapterProgress {
+	t.Helper()
+	time.Sleep(time.Millisecond)
+	progress, err := s.Update(manga, chapter, page, pages)
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	return progress
+}
+
+// history lists the chapter IDs of the reading history in order
+func history(s *Store, query string) string {
+	var ids []string
+	for _, entry := range s.History(query) {
+		ids = append(ids, entry.ChapterID())
+	}
+	return strings.Join(ids, " ")
+}
+
+func TestUpdate(t *testing.T) {
+	s := openTest(t, t.TempDir())
+	manga, chapter := testManga("m1", "Berserk"), testChapter("c1", "1", "")
+
+	tests := []struct {
+		name          string
+		page          int
+		wantCompleted bool
+	}{
+		{"first page", 0, false},
+		{"middle", 5, false},
+		{"last page", 9, true},
+		{"paging back", 3, true},
+	}
+	for _, tt := range tests {
+		progress := read(t, s, manga, chapter, tt.page, 10)
+		got, ok := s.Get("m1", "c1")
+		if !ok {
+			t.Fatalf("%s: Get() found nothing", tt.name)
+		}
+		if got.Page != tt.page || got.Pages != 10 || got.Completed != tt.wantCompleted || !got.UpdatedAt.Equal(progress.UpdatedAt) {
+			t.Errorf("%s: Get() = %+v, Update() = %+v", tt.name, got, progress)
+		}
+	}
+
+	if _, ok := s.Get("m1", "missing"); ok {
+		t.Error("Get() found a chapter never read")
+	}
+	if got := read(t, s, manga, testChapter("c2", "2", ""), 0, 0); got.Completed {
+		t.Error("chapter without pages completed")
+	}
+}
+
+func TestResume(t *testing.T) {
+	s := openTest(t, t.TempDir())
+	manga := testManga("m1", "Berserk")
+
+	if _, ok := s.Latest("m1"); ok {
+		t.Error("Latest() found a chapter before reading")
+	}
+
+	read(t, s, manga, testChapter("c1", "1", ""), 9, 10)
+	read(t, s, manga, testChapter("c3", "3", ""), 4, 10)
+	read(t, s, manga, testChapter("c2", "2", ""), 2, 10)
+
+	// Chapters marked read elsewhere are not where reading resumes
+	if _, err := s.MarkRead(manga, []string{"c4"}); err != nil {
+		t.Fatalf("MarkRead() error = %v", err)
+	}
+
+	latest, ok := s.Latest("m1")
+	if !ok || latest.ChapterID() != "c2" || latest.Page != 2 {
+		t.Errorf("Latest() = %+v, %t, want c2 at page 2", latest, ok)
+	}
+	if got := len(s.ForManga("m1")); got != 4 {
+		t.Errorf("ForManga() returned %d chapters, want 4", got)
+	}
+}
+
+func TestMarkRead(t *testing.T) {
+	s := openTest(t, t.TempDir())
+	manga := testManga("m1", "Berserk")
+	read(t, s, manga, testChapter("c1", "1", ""), 9, 10)
+	read(t, s, manga, testChapter("c2", "2", ""), 3, 10)
+
+	changed, err := s.MarkRead(manga, []string{"c1", "c2", "c3"})
+	if err != nil {
+		t.Fatalf("MarkRead() error = %v", err)
+	}
+	if changed != 2 {
+		t.Errorf("MarkRead() = %d, want 2", changed)
+	}
+	if got, _ := s.Get("m1", "c2"); !got.Completed || got.Page != 3 {
+		t.Errorf("Get() after MarkRead() = %+v, want completed at page 3", got)
+	}
+
+	if changed, err := s.MarkRead(manga, []string{"c1", "c3"}); err != nil || changed != 0 {
+		t.Errorf("MarkRead() again = %d, %v, want 0", changed, err)
+	}
+	if got := strings.Join(s.CompletedIDs("m1"), " "); got != "c1 c2 c3" {
+		t.Errorf("CompletedIDs() = %s, want c1 c2 c3", got)
+	}
+	if got := s.ReadCount("m1"); got != 3 {
+		t.Errorf("ReadCount() = %d, want 3", got)
+	}
+	if got := s.ReadCount("m2"); got != 0 {
+		t.Errorf("ReadCount() of an unread manga = %d", got)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	s := openTest(t, t.TempDir())
+	berserk, monster := testManga("m1", "Berserk"), testManga("m2", "Monster")
+	read(t, s, berserk, testChapter("c1", "1", "The Black Swordsman"), 9, 10)
+	read(t, s, monster, testChapter("c2", "1", "Herr Doktor Tenma"), 3, 10)
+	read(t, s, berserk, testChapter("c3", "2", "The Brand"), 1, 10)
+	if _, err := s.MarkRead(berserk, []string{"c4"}); err != nil {
+		t.Fatalf("MarkRead() error = %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "c3 c2 c1"},
+		{"berserk", "c3 c1"},
+		{" DOKTOR ", "c2"},
+		{"2", "c3"},
+		{"nothing", ""},
+	}
+	for _, tt := range tests {
+		if got := history(s, tt.query); got != tt.want {
+			t.Errorf("History(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+	if entries := s.History("monster"); len(entries) != 1 || entries[0].Title() != "Monster" {
+		t.Errorf("History() entries = %+v", entries)
+	}
+
+	// Removing from the history keeps completed chapters completed
+	if err := s.RemoveHistory("m1", "c1"); err != nil {
+		t.Fatalf("RemoveHistory() error = %v", err)
+	}
+	if got := history(s, ""); got != "c3 c2" {
+		t.Errorf("History() after RemoveHistory() = %s, want c3 c2", got)
+	}
+	if got, ok := s.Get("m1", "c1"); !ok || !got.Completed || got.Page != 0 {
+		t.Errorf("Get() after RemoveHistory() = %+v, %t, want completed", got, ok)
+	}
+
+	if err := s.ClearHistory(); err != nil {
+		t.Fatalf("ClearHistory() error = %v", err)
+	}
+	if got := history(s, ""); got != "" {
+		t.Errorf("History() after ClearHistory() = %s", got)
+	}
+	if _, ok := s.Get("m2", "c2"); ok {
+		t.Error("unfinished chapter kept after ClearHistory()")
+	}
+	if got := strings.Join(s.CompletedIDs("m1"), " "); got != "c1 c4" {
+		t.Errorf("CompletedIDs() after ClearHistory() = %s, want c1 c4", got)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	s := openTest(t, dir)
+	manga := testManga("m1", "Berserk")
+	read(t, s, manga, testChapter("c1", "1", ""), 9, 10)
+	want := read(t, s, manga, testChapter("c2", "2", ""), 4, 10)
+	if err := s.RemoveHistory("m1", "c1"); err != nil {
+		t.Fatalf("RemoveHistory() error = %v", err)
+	}
+
+	reopened := openTest(t, dir)
+	latest, ok := reopened.Latest("m1")
+	if !ok || latest.ChapterID() != "c2" || latest.Page != 4 || !latest.UpdatedAt.Equal(want.UpdatedAt) {
+		t.Errorf("Latest() after reopening = %+v, %t, want %+v", latest, ok, want)
+	}
+	if got := strings.Join(reopened.CompletedIDs("m1"), " "); got != "c1" {
+		t.Errorf("CompletedIDs() after reopening = %s, want c1", got)
+	}
+	if got := history(reopened, ""); got != "c2" {
+		t.Errorf("History() after reopening = %s, want c2", got)
+	}
+	if got := reopened.Manga()["m1"].Title(); got != "Berserk" {
+		t.Errorf("Manga() after reopening = %q, want Berserk", got)
+	}
+}
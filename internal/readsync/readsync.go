@@ -0,0 +1,355 @@
+// Package readsync keeps local reading progress and the read markers of the
+// logged in MangaDex account in step.
+//
+// Conflicts always resolve the same way: a chapter read on either side ends
+// up read on both. Remote markers complete local chapters without touching
+// their page or read time, and local progress never unmarks a chapter on
+// MangaDex.
+package readsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/api"
+	"github.com/sangnt1552314/mangadex-tui/internal/auth"
+	"github.com/sangnt1552314/mangadex-tui/internal/library"
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+	"github.com/sangnt1552314/mangadex-tui/internal/progress"
+	"github.com/sangnt1552314/mangadex-tui/internal/storage"
+)
+
+const (
+	queueFileName = "read_queue.json"
+
+	// batchSize is the most IDs sent in one request
+	batchSize = 100
+
+	// flushInterval is how often queued markers are retried
+	flushInterval = time.Minute
+
+	// flushDelay collects markers set in quick succession into one push
+	flushDelay = 2 * time.Second
+)
+
+// Service pulls read markers into the progress store and pushes chapters
+// read locally back to MangaDex. Pushes wait in a queue persisted in the
+// user data directory until they succeed, so reading offline or while
+// logged out loses nothing.
+type Service struct {
+	mu       sync.Mutex
+	path     string
+	client   *api.Client
+	auth     *auth.Authenticator
+	progress *progress.Store
+	library  *library.Library
+	queue    map[string][]string
+	wake     chan struct{}
+	offline  func() bool
+}
+
+// Open loads the push queue from dir, starting empty if none exists yet
+func Open(dir string, client *api.Client, authenticator *auth.Authenticator, store *progress.Store, lib *library.Library) (*Service, error) {
+	s := &Service{
+		path:     filepath.Join(dir, queueFileName),
+		client:   client,
+		auth:     authenticator,
+		progress: store,
+		library:  lib,
+		queue:    make(map[string][]string),
+		wake:     make(chan struct{}, 1),
+	}
+
+	if _, err := storage.ReadJSON(s.path, &s.queue); err != nil {
+		return nil, err
+	}
+	if s.queue == nil {
+		s.queue = make(map[string][]string)
+	}
+
+	return s, nil
+}
+
+// Pending returns how many chapters are waiting to be pushed
+func (s *Service) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, ids := range s.queue {
+		count += len(ids)
+	}
+	return count
+}
+
+// MarkRead queues chapters of a manga to be marked read on MangaDex
+func (s *Service) MarkRead(mangaID string, chapterIDs ...string) {
+	if err := s.enqueue(mangaID, chapterIDs); err != nil {
+		log.Println("Error queueing read markers:", err)
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Pull fetches the read markers of a manga and merges them into local
+// progress. It returns how many chapters became read locally and does
+// nothing while logged out.
+func (s *Service) Pull(ctx context.Context, manga models.Manga) (int, error) {
+	if !s.auth.LoggedIn() {
+		return 0, nil
+	}
+
+	remote, err := s.client.GetReadMarkers(ctx, manga.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch read markers: %w", err)
+	}
+
+	return s.merge(manga, remote)
+}
+
+// PullAll merges the read markers of every manga with local progress,
+// asking for them in batches
+func (s *Service) PullAll(ctx context.Context) error {
+	if !s.auth.LoggedIn() {
+		return nil
+	}
+
+	manga := s.progress.Manga()
+	ids := make([]string, 0, len(manga))
+	for id := range manga {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, batch := range batches(ids) {
+		markers, err := s.client.GetReadMarkersBatch(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("failed to fetch read markers: %w", err)
+		}
+
+		for _, id := range batch {
+			if _, err := s.merge(manga[id], markers[id]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Flush pushes queued markers. Markers that fail for a reason that may pass,
+// such as a network error, stay queued for the next attempt, while those
+// MangaDex rejects are dropped. A manga that fails does not hold up the
+// others. It does nothing while logged out.
+func (s *Service) Flush(ctx context.Context) error {
+	if !s.auth.LoggedIn() {
+		return nil
+	}
+
+	s.mu.Lock()
+	pending := make(map[string][]string, len(s.queue))
+	mangaIDs := make([]string, 0, len(s.queue))
+	for mangaID, ids := range s.queue {
+		pending[mangaID] = append([]string{}, ids...)
+		mangaIDs = append(mangaIDs, mangaID)
+	}
+	s.mu.Unlock()
+	sort.Strings(mangaIDs)
+
+	var errs []error
+	for _, mangaID := range mangaIDs {
+		for _, batch := range batches(pending[mangaID]) {
+			err := s.client.UpdateReadMarkers(ctx, mangaID, models.ReadMarkersUpdate{ChapterIdsRead: batch})
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil && retryable(err) {
+				errs = append(errs, fmt.Errorf("failed to push read markers of %s: %w", mangaID, err))
+				break
+			}
+			if err != nil {
+				// Retrying cannot help, say if the manga is gone from MangaDex
+				log.Printf("Dropping read markers of manga %s: %v", mangaID, err)
+			}
+
+			if err := s.dequeue(mangaID, batch); err != nil {
+				return err
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// retryable reports whether a push that failed with err may work later.
+// Rate limits, expired sessions and server errors pass, other error
+// responses are about the request itself.
+func retryable(err error) bool {
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) {
+		return true
+	}
+	return apiErr.StatusCode >= 500 || api.IsRateLimited(err) || api.IsUnauthorized(err)
+}
+
+// SetOfflineFunc sets a function reporting whether the app is offline, in
+// which case Run leaves MangaDex alone
+func (s *Service) SetOfflineFunc(fn func() bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.offline = fn
+}
+
+func (s *Service) isOffline() bool {
+	s.mu.Lock()
+	offline := s.offline
+	s.mu.Unlock()
+
+	return offline != nil && offline()
+}
+
+// Run pulls all read markers once and then pushes queued markers whenever
+// chapters are marked read, retrying every minute, until ctx is cancelled.
+// While offline nothing is sent, and the pull waits until the app is
+// back online.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	pulled := false
+	for {
+		if !s.isOffline() {
+			if !pulled {
+				err := s.PullAll(ctx)
+				if err != nil && ctx.Err() == nil {
+					log.Println("Error pulling read markers:", err)
+				}
+				pulled = err == nil
+			}
+			if err := s.Flush(ctx); err != nil && ctx.Err() == nil {
+				log.Println("Error pushing read markers:", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(flushDelay):
+			}
+		}
+	}
+}
+
+// merge applies remote markers locally and queues local reads missing remotely
+func (s *Service) merge(manga models.Manga, remote []string) (int, error) {
+	changed, err := s.progress.MarkRead(manga, remote)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save read markers: %w", err)
+	}
+
+	onRemote := make(map[string]bool, len(remote))
+	for _, id := range remote {
+		onRemote[id] = true
+	}
+	var missing []string
+	for _, id := range s.progress.CompletedIDs(manga.ID) {
+		if !onRemote[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		s.MarkRead(manga.ID, missing...)
+	}
+
+	if changed > 0 {
+		readCount := s.progress.ReadCount(manga.ID)
+		if err := s.library.Update(manga.ID, func(entry *library.Entry) {
+			entry.ReadCount = readCount
+		}); err != nil {
+			log.Println("Error updating library entry:", err)
+		}
+	}
+
+	return changed, nil
+}
+
+func (s *Service) enqueue(mangaID string, chapterIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queued := make(map[string]bool, len(s.queue[mangaID]))
+	for _, id := range s.queue[mangaID] {
+		queued[id] = true
+	}
+
+	added := false
+	for _, id := range chapterIDs {
+		if !queued[id] {
+			s.queue[mangaID] = append(s.queue[mangaID], id)
+			queued[id] = true
+			added = true
+		}
+	}
+	if !added {
+		return nil
+	}
+
+	return s.save()
+}
+
+func (s *Service) dequeue(mangaID string, chapterIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sent := make(map[string]bool, len(chapterIDs))
+	for _, id := range chapterIDs {
+		sent[id] = true
+	}
+
+	var remaining []string
+	for _, id := range s.queue[mangaID] {
+		if !sent[id] {
+			remaining = append(remaining, id)
+		}
+	}
+	if len(remaining) == 0 {
+		delete(s.queue, mangaID)
+	} else {
+		s.queue[mangaID] = remaining
+	}
+
+	return s.save()
+}
+
+// save writes the queue to disk; the caller holds the lock
+func (s *Service) save() error {
+	return storage.WriteJSON(s.path, s.queue, 0600)
+}
+
+// batches splits ids into slices of at most batchSize
+func batches(ids []string) [][]string {
+	var result [][]string
+	for len(ids) > batchSize {
+		result = append(result, ids[:batchSize])
+		ids = ids[batchSize:]
+	}
+	if len(ids) > 0 {
+		result = append(result, ids)
+	}
+	return result
+}
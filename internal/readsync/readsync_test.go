@@ -0,0 +1,289 @@
+package readsync
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/api"
+	"github.com/sangnt1552314/mangadex-tui/internal/auth"
+	"github.com/sangnt1552314/mangadex-tui/internal/library"
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+	"github.com/sangnt1552314/mangadex-tui/internal/progress"
+)
+
+// markerServer keeps read markers the way MangaDex does and answers pushes
+// for some manga with a fixed status
+type markerServer struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	read   map[string][]string
+	status map[string]int
+	pushes map[string]int
+}
+
+func newMarkerServer(t *testing.T) *markerServer {
+	t.Helper()
+	s := &markerServer{
+		read:   make(map[string][]string),
+		status: make(map[string]int),
+		pushes: make(map[string]int),
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if r.URL.Path == "/manga/read" {
+			grouped := make(map[string][]string)
+			for _, id := range r.URL.Query()["ids[]"] {
+				grouped[id] = s.read[id]
+			}
+			json.NewEncoder(w).Encode(models.GroupedReadMarkersResponse{Result: "ok", Data: grouped})
+			return
+		}
+
+		mangaID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/manga/"), "/read")
+		if r.Method == http.MethodGet {
+			json.NewEncoder(w).Encode(models.ReadMarkersResponse{Result: "ok", Data: s.read[mangaID]})
+			return
+		}
+
+		s.pushes[mangaID]++
+		if status := s.status[mangaID]; status != 0 {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"result":"error","errors":[]}`))
+			return
+		}
+		var update models.ReadMarkersUpdate
+		json.NewDecoder(r.Body).Decode(&update)
+		for _, id := range update.ChapterIdsRead {
+			if !slices.Contains(s.read[mangaID], id) {
+				s.read[mangaID] = append(s.read[mangaID], id)
+			}
+		}
+		w.Write([]byte(`{"result":"ok"}`))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *markerServer) markers(mangaID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	markers := slices.Clone(s.read[mangaID])
+	slices.Sort(markers)
+	return markers
+}
+
+type fixture struct {
+	dir      string
+	server   *markerServer
+	progress *progress.Store
+	library  *library.Library
+	service  *Service
+}
+
+// newFixture returns a service for a logged in user. Requests are sent to
+// a marker server.
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	f := &fixture{dir: t.TempDir(), server: newMarkerServer(t)}
+
+	store := auth.NewStore(f.dir)
+	session := &auth.Session{Username: "reader", RefreshToken: "token", RefreshExpiresAt: time.Now().Add(time.Hour)}
+	if err := store.Save(session); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	if f.progress, err = progress.Open(f.dir); err != nil {
+		t.Fatal(err)
+	}
+	if f.library, err = library.Open(f.dir); err != nil {
+		t.Fatal(err)
+	}
+	f.service = f.open(t, store)
+	return f
+}
+
+func (f *fixture) open(t *testing.T, store *auth.Store) *Service {
+	t.Helper()
+	client := api.NewClient(api.WithBaseURL(f.server.URL), api.WithMaxRetries(0))
+	service, err := Open(f.dir, client, auth.NewAuthenticator(store), f.progress, f.library)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return service
+}
+
+func testManga(id string) models.Manga {
+	var manga models.Manga
+	manga.ID = id
+	return manga
+}
+
+func TestMarkReadQueue(t *testing.T) {
+	f := newFixture(t)
+
+	f.service.MarkRead("m1", "c1", "c2")
+	f.service.MarkRead("m1", "c2", "c3")
+	f.service.MarkRead("m2", "c9")
+	if got := f.service.Pending(); got != 4 {
+		t.Fatalf("Pending() = %d, want 4", got)
+	}
+
+	// The queue survives a restart
+	reopened := f.open(t, auth.NewStore(f.dir))
+	if got := reopened.Pending(); got != 4 {
+		t.Fatalf("Pending() = %d after reopening, want 4", got)
+	}
+
+	if err := reopened.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if got := reopened.Pending(); got != 0 {
+		t.Errorf("Pending() = %d after flushing, want 0", got)
+	}
+	if got := f.server.markers("m1"); !slices.Equal(got, []string{"c1", "c2", "c3"}) {
+		t.Errorf("markers of m1 = %v", got)
+	}
+	if got := f.server.markers("m2"); !slices.Equal(got, []string{"c9"}) {
+		t.Errorf("markers of m2 = %v", got)
+	}
+}
+
+func TestFlushKeepsGoingPastFailures(t *testing.T) {
+	f := newFixture(t)
+	f.server.status["broken"] = http.StatusServiceUnavailable
+	f.server.status["rejected"] = http.StatusBadRequest
+	f.server.status["gone"] = http.StatusNotFound
+
+	f.service.MarkRead("broken", "c1")
+	f.service.MarkRead("gone", "c2")
+	f.service.MarkRead("ok", "c3")
+	f.service.MarkRead("rejected", "c4")
+
+	err := f.service.Flush(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Flush() error = %v, want the failure of broken", err)
+	}
+	if got := f.server.markers("ok"); !slices.Equal(got, []string{"c3"}) {
+		t.Errorf("markers of ok = %v, want [c3]", got)
+	}
+
+	// Only the markers that may still go through are kept
+	if got := f.service.Pending(); got != 1 {
+		t.Errorf("Pending() = %d, want 1", got)
+	}
+	f.server.mu.Lock()
+	delete(f.server.status, "broken")
+	f.server.mu.Unlock()
+	if err := f.service.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v on retry", err)
+	}
+	if got := f.server.markers("broken"); !slices.Equal(got, []string{"c1"}) {
+		t.Errorf("markers of broken = %v, want [c1]", got)
+	}
+	if got := f.server.pushes["rejected"]; got != 1 {
+		t.Errorf("rejected markers pushed %d times, want 1", got)
+	}
+}
+
+func TestFlushLoggedOut(t *testing.T) {
+	f := newFixture(t)
+	service := f.open(t, auth.NewStore(t.TempDir()))
+
+	service.MarkRead("m1", "c1")
+	if err := service.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := service.Pending(); got != 1 {
+		t.Errorf("Pending() = %d, want the marker kept for later", got)
+	}
+}
+
+func TestPullMerges(t *testing.T) {
+	f := newFixture(t)
+	manga := testManga("m1")
+	if err := f.library.Add(manga, library.CategoryReading); err != nil {
+		t.Fatal(err)
+	}
+
+	// c1 was read on MangaDex, c2 here
+	f.server.read["m1"] = []string{"c1"}
+	if _, err := f.progress.Update(manga, models.Chapter{ID: "c2"}, 4, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := f.service.Pull(context.Background(), manga)
+	if err != nil {
+		t.Fatalf("Pull() error = %v", err)
+	}
+	if changed != 1 {
+		t.Errorf("Pull() = %d, want 1", changed)
+	}
+	if got := f.progress.CompletedIDs("m1"); !slices.Equal(got, []string{"c1", "c2"}) {
+		t.Errorf("CompletedIDs() = %v", got)
+	}
+	if entry, _ := f.library.Get("m1"); entry.ReadCount != 2 {
+		t.Errorf("library ReadCount = %d, want 2", entry.ReadCount)
+	}
+
+	// The local read goes the other way
+	if got := f.service.Pending(); got != 1 {
+		t.Fatalf("Pending() = %d, want 1", got)
+	}
+	if err := f.service.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.server.markers("m1"); !slices.Equal(got, []string{"c1", "c2"}) {
+		t.Errorf("markers = %v", got)
+	}
+}
+
+func TestPullAll(t *testing.T) {
+	f := newFixture(t)
+	for _, id := range []string{"m1", "m2"} {
+		if _, err := f.progress.Update(testManga(id), models.Chapter{ID: id + "-c1"}, 0, 5); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.server.read["m1"] = []string{"m1-c1", "m1-c2"}
+	f.server.read["m2"] = []string{"m2-c1"}
+
+	if err := f.service.PullAll(context.Background()); err != nil {
+		t.Fatalf("PullAll() error = %v", err)
+	}
+	if got := f.progress.CompletedIDs("m1"); !slices.Equal(got, []string{"m1-c1", "m1-c2"}) {
+		t.Errorf("CompletedIDs(m1) = %v", got)
+	}
+	if got := f.progress.CompletedIDs("m2"); !slices.Equal(got, []string{"m2-c1"}) {
+		t.Errorf("CompletedIDs(m2) = %v", got)
+	}
+	if got := f.service.Pending(); got != 0 {
+		t.Errorf("Pending() = %d, want 0", got)
+	}
+}
+
+func TestBatches(t *testing.T) {
+	ids := make([]string, 2*batchSize+1)
+	var sizes []int
+	for _, batch := range batches(ids) {
+		sizes = append(sizes, len(batch))
+	}
+	if !slices.Equal(sizes, []int{batchSize, batchSize, 1}) {
+		t.Errorf("batches() sizes = %v", sizes)
+	}
+	if got := batches(nil); len(got) != 0 {
+		t.Errorf("batches(nil) = %v", got)
+	}
+}
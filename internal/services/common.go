@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"image"
 	"image/jpeg"
@@ -89,8 +90,8 @@ func FormatTextStatus(status string) string {
 	}
 }
 
-func GetMangaImage(mangaID string, size int, isRandomImage bool) image.Image {
-	coverList, err := api.GetMangaCover(mangaID)
+func GetMangaImage(ctx context.Context, client *api.Client, mangaID string, size int, isRandomImage bool) image.Image {
+	coverList, err := client.GetMangaCover(ctx, mangaID)
 	if err != nil {
 		log.Println("Error fetching cover for manga:", err)
 		return nil
@@ -108,125 +109,61 @@ func GetMangaImage(mangaID string, size int, isRandomImage bool) image.Image {
 	}
 
 	coverURL := api.GetCoverURL(mangaID, coverList.Data[randomIndex].Attributes.FileName, size)
-	resp, err := http.Get(coverURL)
+	img, err := fetchImage(ctx, client, coverURL)
 	if err != nil {
 		log.Println("Error fetching cover image:", err)
 		return nil
 	}
-	defer resp.Body.Close()
-
-	imgData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Println("Error reading cover image data:", err)
-		return nil
-	}
-	contentType := http.DetectContentType(imgData)
 
-	var img image.Image
-	switch contentType {
-	case "image/jpeg":
-		img, err = jpeg.Decode(bytes.NewReader(imgData))
-	case "image/png":
-		img, err = png.Decode(bytes.NewReader(imgData))
-	default:
-		log.Printf("Unsupported image type: %s", contentType)
-		return nil
-	}
+	return img
+}
 
+func GetMangaImageByFilename(ctx context.Context, client *api.Client, mangaID string, filename string, size int) image.Image {
+	coverURL := api.GetCoverURL(mangaID, filename, size)
+	img, err := fetchImage(ctx, client, coverURL)
 	if err != nil {
-		log.Printf("Error decoding image: %v", err)
+		log.Println("Error fetching cover image:", err)
 		return nil
 	}
 
 	return img
 }
 
-func GetMangaImageByFilename(mangaID string, filename string, size int) image.Image {
-	coverURL := api.GetCoverURL(mangaID, filename, size)
-	resp, err := http.Get(coverURL)
+// fetchImage downloads and decodes a JPEG or PNG image
+func fetchImage(ctx context.Context, client *api.Client, imageURL string) (image.Image, error) {
+	resp, err := client.FetchURL(ctx, imageURL)
 	if err != nil {
-		log.Println("Error fetching cover image:", err)
-		return nil
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	imgData, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Println("Error reading cover image data:", err)
-		return nil
+		return nil, fmt.Errorf("failed to read image data: %w", err)
 	}
+
+	return decodeImage(imgData)
+}
+
+func decodeImage(imgData []byte) (image.Image, error) {
 	contentType := http.DetectContentType(imgData)
 
 	var img image.Image
+	var err error
 	switch contentType {
 	case "image/jpeg":
 		img, err = jpeg.Decode(bytes.NewReader(imgData))
 	case "image/png":
 		img, err = png.Decode(bytes.NewReader(imgData))
 	default:
-		log.Printf("Unsupported image type: %s", contentType)
-		return nil
+		return nil, fmt.Errorf("unsupported image type: %s", contentType)
 	}
 
 	if err != nil {
-		log.Printf("Error decoding image: %v", err)
-		return nil
+		return nil, fmt.Errorf("failed to decode image: %w", err)
 	}
 
-	return img
-}
-
-func GetImagesByChapterId(chapterId string) ([]image.Image, error) {
-	imageResponse, err := api.GetChapterImageResponse(chapterId)
-	if err != nil {
-		log.Println("Error fetching chapter images:", err)
-		return nil, err
-	}
-	if len(imageResponse.Chapter.Data) == 0 {
-		log.Println("No images found for chapter:", chapterId)
-		return nil, nil
-	}
-	var images []image.Image
-	for _, imageName := range imageResponse.Chapter.Data {
-		imageURL := fmt.Sprintf("%s/data/%s/%s", imageResponse.BaseURL, imageResponse.Chapter.Hash, imageName)
-		log.Printf("Fetching image: %s", imageURL)
-		resp, err := http.Get(imageURL)
-		if err != nil {
-			log.Println("Error fetching chapter image:", err)
-			continue
-		}
-		defer resp.Body.Close()
-
-		imgData, err := io.ReadAll(resp.Body)
-		if err != nil {
-			log.Println("Error reading chapter image data:", err)
-			continue
-		}
-		contentType := http.DetectContentType(imgData)
-
-		var img image.Image
-		switch contentType {
-		case "image/jpeg":
-			img, err = jpeg.Decode(bytes.NewReader(imgData))
-		case "image/png":
-			img, err = png.Decode(bytes.NewReader(imgData))
-		default:
-			log.Printf("Unsupported image type: %s", contentType)
-			continue
-		}
-
-		if err != nil {
-			log.Printf("Error decoding chapter image: %v", err)
-			continue
-		}
-
-		images = append(images, img)
-	}
-	if len(images) == 0 {
-		log.Println("No valid images found for chapter:", chapterId)
-		return nil, nil
-	}
-	return images, nil
+	return img, nil
 }
 
 func GetCoverFileName(manga models.Manga) string {
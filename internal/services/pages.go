@@ -0,0 +1,535 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"image"
+	"log"
+	"slices"
+	"sync"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/api"
+)
+
+const (
+	// DefaultPageWorkers is how many pages are fetched at once
+	DefaultPageWorkers = 4
+
+	// DefaultPageReadAhead is how many pages from the one being read are
+	// fetched ahead. It stays well below the cache size, so fetching ahead
+	// never evicts the pages about to be shown.
+	DefaultPageReadAhead = 8
+
+	// DefaultPageCacheSize is how many decoded pages are kept in memory
+	DefaultPageCacheSize = 48
+)
+
+type pageState int
+
+const (
+	pageIdle pageState = iota
+	pageLoading
+	pageReady
+	pageFailed
+)
+
+// pageSlot tracks one page of a chapter. done is closed when the page
+// leaves the loading state and replaced whenever it becomes idle again.
+type pageSlot struct {
+	state pageState
+	err   error
+	done  chan struct{}
+}
+
+// LocalPages are the pages of a chapter kept on disk, such as a downloaded
+// archive, decoded one at a time
+type LocalPages interface {
+	Count() int
+	Page(i int) (image.Image, error)
+	Close() error
+}
+
+// PagePipeline fetches chapter pages from MangaDex@Home, or decodes them
+// from disk, with bounded concurrency. Pages are fetched in reading order
+// within a window that moves along with the page being read, pages asked
+// for are moved to the front, and decoded images are kept in an LRU cache
+// so memory use stays flat however long a chapter is.
+type PagePipeline struct {
+	ctx       context.Context
+	client    *api.Client
+	sem       chan struct{}
+	readAhead int
+	cache     *imageCache
+
+	mu       sync.Mutex
+	chapters map[string]*ChapterPages
+}
+
+// NewPagePipeline returns a pipeline fetching at most workers pages at once,
+// up to readAhead pages past the one being read, and keeping cacheSize
+// decoded pages. Fetches stop when ctx is cancelled.
+func NewPagePipeline(ctx context.Context, client *api.Client, workers, readAhead, cacheSize int) *PagePipeline {
+	if workers < 1 {
+		workers = 1
+	}
+	if readAhead < 1 {
+		readAhead = 1
+	}
+
+	return &PagePipeline{
+		ctx:       ctx,
+		client:    client,
+		sem:       make(chan struct{}, workers),
+		readAhead: readAhead,
+		cache:     newImageCache(cacheSize),
+		chapters:  make(map[string]*ChapterPages),
+	}
+}
+
+// Open returns the pages of a chapter and starts fetching its first pages,
+// as compressed data-saver images if dataSaver is set. Every other chapter
+// stops fetching. Pages they fetched stay cached, so a prefetched chapter
+// still opens instantly.
+func (p *PagePipeline) Open(ctx context.Context, chapterID string, dataSaver bool) (*ChapterPages, error) {
+	chapter, err := p.chapter(ctx, chapterID, dataSaver)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.closeOthersLocked(chapter.id)
+	p.mu.Unlock()
+
+	chapter.setWindow(0, p.readAhead)
+	return chapter, nil
+}
+
+// OpenLocal returns the pages of a chapter kept on disk, decoding them
+// within the same read-ahead window and cache as pages fetched online.
+// Every other chapter stops, as with Open.
+func (p *PagePipeline) OpenLocal(chapterID string, pages LocalPages) *ChapterPages {
+	chapter := newChapterPages(p, "local/"+chapterID, chapterID, pages.Count())
+	chapter.local = pages
+
+	p.mu.Lock()
+	if old, ok := p.chapters[chapter.id]; ok {
+		old.close()
+	}
+	p.chapters[chapter.id] = chapter
+	p.closeOthersLocked(chapter.id)
+	p.mu.Unlock()
+
+	chapter.setWindow(0, p.readAhead)
+	return chapter
+}
+
+// Prefetch fetches the first pages of a chapter so it opens instantly
+func (p *PagePipeline) Prefetch(ctx context.Context, chapterID string, dataSaver bool, pages int) error {
+	chapter, err := p.chapter(ctx, chapterID, dataSaver)
+	if err != nil {
+		return err
+	}
+
+	chapter.setWindow(0, pages)
+	return nil
+}
+
+// Close stops every fetch in progress. Cached pages are kept.
+func (p *PagePipeline) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for id, chapter := range p.chapters {
+		chapter.close()
+		delete(p.chapters, id)
+	}
+}
+
+// closeOthersLocked stops every chapter but the one with key; the caller
+// holds the lock
+func (p *PagePipeline) closeOthersLocked(key string) {
+	for id, other := range p.chapters {
+		if id != key {
+			other.close()
+			delete(p.chapters, id)
+		}
+	}
+}
+
+// chapter returns the open chapter with chapterID, asking MangaDex@Home
+// where its pages are if needed
+func (p *PagePipeline) chapter(ctx context.Context, chapterID string, dataSaver bool) (*ChapterPages, error) {
+	key := chapterID
+	if dataSaver {
+		key += "/data-saver"
+	}
+
+	p.mu.Lock()
+	chapter, ok := p.chapters[key]
+	p.mu.Unlock()
+	if ok {
+		return chapter, nil
+	}
+
+	imageResponse, err := p.client.GetChapterImageResponse(ctx, chapterID)
+	if err != nil {
+		return nil, err
+	}
+	urls := imageResponse.PageURLs(dataSaver)
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("chapter %s has no pages: %w", chapterID, api.ErrNoResults)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Opened by someone else in the meantime
+	if chapter, ok := p.chapters[key]; ok {
+		return chapter, nil
+	}
+
+	chapter = newChapterPages(p, key, chapterID, len(urls))
+	chapter.dataSaver = dataSaver
+	chapter.urls = urls
+	p.chapters[key] = chapter
+	return chapter, nil
+}
+
+// ChapterPages are the pages of one chapter, fetched in the background
+type ChapterPages struct {
+	pipeline  *PagePipeline
+	id        string
+	chapterID string
+	dataSaver bool
+	local     LocalPages
+	count     int
+	ctx       context.Context
+	cancel    context.CancelFunc
+
+	// nodeMu serialises asking for a new MangaDex@Home node
+	nodeMu sync.Mutex
+
+	// Pages in [from, to) are fetched in reading order
+	mu       sync.Mutex
+	urls     []string
+	pages    []*pageSlot
+	priority []int
+	from, to int
+	workers  int
+}
+
+func newChapterPages(pipeline *PagePipeline, id, chapterID string, count int) *ChapterPages {
+	ctx, cancel := context.WithCancel(pipeline.ctx)
+	c := &ChapterPages{
+		pipeline:  pipeline,
+		id:        id,
+		chapterID: chapterID,
+		count:     count,
+		ctx:       ctx,
+		cancel:    cancel,
+		pages:     make([]*pageSlot, count),
+	}
+	for i := range c.pages {
+		c.pages[i] = &pageSlot{done: make(chan struct{})}
+	}
+	return c
+}
+
+// Count returns the number of pages
+func (c *ChapterPages) Count() int {
+	return c.count
+}
+
+// Page returns page i, waiting for it to be fetched if needed, and moves
+// the read-ahead window to start there. A failed page reports its error
+// once and is fetched again on the next call.
+func (c *ChapterPages) Page(ctx context.Context, i int) (image.Image, error) {
+	if i < 0 || i >= c.count {
+		return nil, fmt.Errorf("page %d out of range", i+1)
+	}
+	c.setWindow(i, i+c.pipeline.readAhead)
+
+	for {
+		if img, ok := c.pipeline.cache.get(c.key(i)); ok {
+			return img, nil
+		}
+
+		c.mu.Lock()
+		page := c.pages[i]
+		switch page.state {
+		case pageFailed:
+			// Report the failure once, the next call fetches the page again
+			err := page.err
+			c.reset(page)
+			c.mu.Unlock()
+			return nil, err
+		case pageReady:
+			// Evicted from the cache
+			c.reset(page)
+			c.request(i)
+		case pageIdle:
+			c.request(i)
+		}
+		done := page.done
+		c.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-done:
+		}
+	}
+}
+
+// setWindow fetches the pages in [from, to) in reading order, in place of
+// the pages of the previous window not fetched yet
+func (c *ChapterPages) setWindow(from, to int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.from = max(0, from)
+	c.to = min(to, c.count)
+	c.startWorkers()
+}
+
+// request fetches page i ahead of the reading order; the caller holds the lock
+func (c *ChapterPages) request(i int) {
+	if c.ctx.Err() != nil {
+		// The chapter was closed, fetch again on behalf of the caller
+		c.ctx, c.cancel = context.WithCancel(c.pipeline.ctx)
+	}
+	c.priority = append(c.priority, i)
+	c.startWorkers()
+}
+
+// startWorkers runs up to one worker per pipeline slot; the caller holds the lock
+func (c *ChapterPages) startWorkers() {
+	for c.workers < cap(c.pipeline.sem) {
+		c.workers++
+		go c.work()
+	}
+}
+
+func (c *ChapterPages) work() {
+	for {
+		i, ctx, ok := c.next()
+		if !ok {
+			return
+		}
+
+		select {
+		case c.pipeline.sem <- struct{}{}:
+		case <-ctx.Done():
+			c.finish(ctx, i, nil, ctx.Err())
+			continue
+		}
+
+		img, err := c.fetch(ctx, i)
+		<-c.pipeline.sem
+
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Error fetching page %d of chapter %s: %v", i+1, c.id, err)
+		}
+		c.finish(ctx, i, img, err)
+	}
+}
+
+// fetch downloads and decodes page i. If the MangaDex@Home node fails, a
+// new one is asked for and the page is tried once more.
+func (c *ChapterPages) fetch(ctx context.Context, i int) (image.Image, error) {
+	if c.local != nil {
+		return c.local.Page(i)
+	}
+
+	pageURL := c.url(i)
+	data, err := c.pipeline.client.FetchAtHome(ctx, pageURL)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("Page %d of chapter %s failed, retrying on a new node: %v", i+1, c.id, err)
+		if nodeErr := c.changeNode(ctx, pageURL); nodeErr != nil {
+			log.Printf("Error getting a new node for chapter %s: %v", c.id, nodeErr)
+			return nil, err
+		}
+		data, err = c.pipeline.client.FetchAtHome(ctx, c.url(i))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeImage(data)
+}
+
+func (c *ChapterPages) url(i int) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.urls[i]
+}
+
+// changeNode asks MangaDex@Home for a new node after failedURL could not be
+// fetched. Pages failing together only ask once.
+func (c *ChapterPages) changeNode(ctx context.Context, failedURL string) error {
+	c.nodeMu.Lock()
+	defer c.nodeMu.Unlock()
+
+	c.mu.Lock()
+	changed := !slices.Contains(c.urls, failedURL)
+	c.mu.Unlock()
+	if changed {
+		return nil
+	}
+
+	imageResponse, err := c.pipeline.client.GetChapterImageResponse(ctx, c.chapterID)
+	if err != nil {
+		return err
+	}
+	urls := imageResponse.PageURLs(c.dataSaver)
+	if len(urls) != c.count {
+		return fmt.Errorf("chapter %s changed from %d to %d pages", c.chapterID, c.count, len(urls))
+	}
+
+	c.mu.Lock()
+	c.urls = urls
+	c.mu.Unlock()
+	return nil
+}
+
+// next picks the page to fetch: requested pages first, then the first idle
+// page of the window. A worker with nothing left to do is counted out
+// under the same lock, so a request never waits on a worker about to exit.
+func (c *ChapterPages) next() (int, context.Context, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ctx.Err() == nil {
+		for len(c.priority) > 0 {
+			i := c.priority[0]
+			c.priority = c.priority[1:]
+			if c.pages[i].state == pageIdle {
+				c.pages[i].state = pageLoading
+				return i, c.ctx, true
+			}
+		}
+
+		for i := c.from; i < c.to; i++ {
+			if c.pages[i].state != pageIdle {
+				continue
+			}
+			if _, cached := c.pipeline.cache.get(c.key(i)); cached {
+				continue
+			}
+			c.pages[i].state = pageLoading
+			return i, c.ctx, true
+		}
+	}
+
+	c.workers--
+	return 0, nil, false
+}
+
+// finish records the result of fetching page i. Pages whose fetch was
+// cancelled become idle again instead of failing.
+func (c *ChapterPages) finish(ctx context.Context, i int, img image.Image, err error) {
+	if err == nil {
+		c.pipeline.cache.put(c.key(i), img)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	page := c.pages[i]
+	switch {
+	case err == nil:
+		page.state = pageReady
+	case ctx.Err() != nil:
+		page.state = pageIdle
+	default:
+		page.state = pageFailed
+		page.err = err
+	}
+
+	// Wake up anyone waiting, an idle page gets a new channel
+	close(page.done)
+	if page.state == pageIdle {
+		page.done = make(chan struct{})
+	}
+}
+
+// reset makes a page fetchable again; the caller holds the lock
+func (c *ChapterPages) reset(page *pageSlot) {
+	page.state = pageIdle
+	page.err = nil
+	page.done = make(chan struct{})
+}
+
+// close stops fetching and lets go of the files of local pages, which
+// are opened again if the chapter is read on
+func (c *ChapterPages) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cancel()
+	if c.local != nil {
+		if err := c.local.Close(); err != nil {
+			log.Printf("Error closing chapter %s: %v", c.id, err)
+		}
+	}
+}
+
+func (c *ChapterPages) key(i int) string {
+	return fmt.Sprintf("%s/%d", c.id, i)
+}
+
+// imageCache is a fixed size LRU of decoded images
+type imageCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+type imageCacheEntry struct {
+	key string
+	img image.Image
+}
+
+func newImageCache(capacity int) *imageCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &imageCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *imageCache) get(key string) (image.Image, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*imageCacheEntry).img, true
+}
+
+func (c *imageCache) put(key string, img image.Image) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		element.Value.(*imageCacheEntry).img = img
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&imageCacheEntry{key: key, img: img})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*imageCacheEntry).key)
+	}
+}
@@ -1,27 +1,72 @@
 package ui
 
 import (
+	"context"
+	"log"
+	"sync/atomic"
+
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 
+	"github.com/sangnt1552314/mangadex-tui/internal/api"
+	"github.com/sangnt1552314/mangadex-tui/internal/auth"
+	"github.com/sangnt1552314/mangadex-tui/internal/cache"
+	"github.com/sangnt1552314/mangadex-tui/internal/components"
+	"github.com/sangnt1552314/mangadex-tui/internal/config"
+	"github.com/sangnt1552314/mangadex-tui/internal/download"
+	"github.com/sangnt1552314/mangadex-tui/internal/graphics"
+	"github.com/sangnt1552314/mangadex-tui/internal/library"
+	"github.com/sangnt1552314/mangadex-tui/internal/progress"
+	"github.com/sangnt1552314/mangadex-tui/internal/readsync"
+	"github.com/sangnt1552314/mangadex-tui/internal/services"
 	"github.com/sangnt1552314/mangadex-tui/internal/ui/interfaces"
 	"github.com/sangnt1552314/mangadex-tui/internal/ui/pages"
 )
 
+// Dependencies are the long-lived services shared by all pages
+type Dependencies struct {
+	Client     *api.Client
+	Auth       *auth.Authenticator
+	Settings   *config.Settings
+	Cache      *cache.Cache
+	Library    *library.Library
+	Progress   *progress.Store
+	Sync       *readsync.Service
+	Downloader *download.Downloader
+	Downloads  *download.Manager
+	Terminal   graphics.Capabilities
+}
+
 type App struct {
 	*tview.Application
 	Pages       *tview.Pages
 	pageObjects map[string]interfaces.Page
+	deps        Dependencies
+	pipeline    *services.PagePipeline
+	images      *components.ImageLayer
+	ctx         context.Context
+	cancel      context.CancelFunc
+	offline     atomic.Bool
 }
 
 var _ interfaces.AppInterface = (*App)(nil)
 
-func NewApp() *App {
+func NewApp(deps Dependencies, offline bool) *App {
+	ctx, cancel := context.WithCancel(context.Background())
+
 	app := &App{
 		Application: tview.NewApplication(),
 		Pages:       tview.NewPages(),
 		pageObjects: make(map[string]interfaces.Page),
+		deps:        deps,
+		pipeline:    services.NewPagePipeline(ctx, deps.Client, services.DefaultPageWorkers, services.DefaultPageReadAhead, services.DefaultPageCacheSize),
+		images:      components.NewImageLayer(imageProtocol(deps.Settings, deps.Terminal), deps.Terminal),
+		ctx:         ctx,
+		cancel:      cancel,
 	}
+	app.offline.Store(offline)
+	app.images.SetDither(deps.Settings.Dither)
+	app.images.SetQueueFunc(app.QueueUpdateDraw)
 
 	app.setupBindings()
 	app.setupPages()
@@ -29,15 +74,97 @@ func NewApp() *App {
 	return app
 }
 
+// imageProtocol picks how images are drawn, using the probed protocol
+// unless the settings name one
+func imageProtocol(settings *config.Settings, terminal graphics.Capabilities) graphics.Protocol {
+	protocol, err := graphics.ParseProtocol(settings.ImageProtocol)
+	if err != nil {
+		log.Println("Error reading image protocol setting:", err)
+		protocol = graphics.Auto
+	}
+	if protocol == graphics.Auto {
+		protocol = terminal.Protocol
+	}
+	log.Printf("Drawing images with %s", protocol)
+	return protocol
+}
+
 func (a *App) Run() error {
 	return a.Application.Run()
 }
 
 func (a *App) Stop() {
+	a.cancel()
 	a.Application.Stop()
 }
 
+// Context returns the application context, cancelled when the app stops
+func (a *App) Context() context.Context {
+	return a.ctx
+}
+
+// Offline reports whether pages should only use local data
+func (a *App) Offline() bool {
+	return a.offline.Load()
+}
+
+// SetOffline switches offline mode on or off
+func (a *App) SetOffline(offline bool) {
+	if a.offline.Swap(offline) != offline {
+		log.Printf("Offline mode: %t", offline)
+	}
+}
+
+func (a *App) Client() *api.Client {
+	return a.deps.Client
+}
+
+func (a *App) Auth() *auth.Authenticator {
+	return a.deps.Auth
+}
+
+func (a *App) Settings() *config.Settings {
+	return a.deps.Settings
+}
+
+func (a *App) Cache() *cache.Cache {
+	return a.deps.Cache
+}
+
+func (a *App) Library() *library.Library {
+	return a.deps.Library
+}
+
+func (a *App) Progress() *progress.Store {
+	return a.deps.Progress
+}
+
+func (a *App) Sync() *readsync.Service {
+	return a.deps.Sync
+}
+
+func (a *App) Downloader() *download.Downloader {
+	return a.deps.Downloader
+}
+
+func (a *App) Downloads() *download.Manager {
+	return a.deps.Downloads
+}
+
+// Images returns the layer drawing images with terminal graphics
+func (a *App) Images() *components.ImageLayer {
+	return a.images
+}
+
+// PagePipeline returns the pipeline fetching chapter pages for the reader
+func (a *App) PagePipeline() *services.PagePipeline {
+	return a.pipeline
+}
+
 func (a *App) setupBindings() {
+	a.Application.SetBeforeDrawFunc(a.images.BeforeDraw)
+	a.Application.SetAfterDrawFunc(a.images.AfterDraw)
+
 	a.Application.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		switch event.Key() {
 		case tcell.KeyCtrlC:
@@ -56,6 +183,11 @@ func (a *App) setupPages() {
 	a.RegisterPage(pages.NewDetailPage(a))
 	a.RegisterPage(pages.NewSearchPage(a))
 	a.RegisterPage(pages.NewReaderPage(a))
+	a.RegisterPage(pages.NewLoginPage(a))
+	a.RegisterPage(pages.NewLibraryPage(a))
+	a.RegisterPage(pages.NewHistoryPage(a))
+	a.RegisterPage(pages.NewDownloadsPage(a))
+	a.RegisterPage(pages.NewSettingsPage(a))
 
 	a.SwitchToPage("home")
 }
@@ -69,7 +201,17 @@ func (a *App) GetPageObject(name string) interfaces.Page {
 }
 
 func (a *App) SwitchToPage(name string) {
+	if current, _ := a.Pages.GetFrontPage(); current != name {
+		if page, ok := a.pageObjects[current].(interfaces.Lifecycle); ok {
+			page.OnLeave()
+		}
+	}
+
 	a.Pages.SwitchToPage(name)
+
+	if page, ok := a.pageObjects[name].(interfaces.Lifecycle); ok {
+		page.OnEnter()
+	}
 }
 
 func (a *App) RegisterPage(page interfaces.Page) {
@@ -90,6 +232,14 @@ func (a *App) SetRoot(root tview.Primitive, fullscreen bool) {
 	a.Application.SetRoot(root, fullscreen)
 }
 
+func (a *App) SetFocus(p tview.Primitive) {
+	a.Application.SetFocus(p)
+}
+
 func (a *App) RestorePages() {
 	a.Application.SetRoot(a.Pages, true)
 }
+
+func (a *App) QueueUpdateDraw(f func()) {
+	a.Application.QueueUpdateDraw(f)
+}
@@ -1,8 +1,21 @@
 package interfaces
 
 import (
+	"context"
+
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/api"
+	"github.com/sangnt1552314/mangadex-tui/internal/auth"
+	"github.com/sangnt1552314/mangadex-tui/internal/cache"
+	"github.com/sangnt1552314/mangadex-tui/internal/components"
+	"github.com/sangnt1552314/mangadex-tui/internal/config"
+	"github.com/sangnt1552314/mangadex-tui/internal/download"
+	"github.com/sangnt1552314/mangadex-tui/internal/library"
+	"github.com/sangnt1552314/mangadex-tui/internal/progress"
+	"github.com/sangnt1552314/mangadex-tui/internal/readsync"
+	"github.com/sangnt1552314/mangadex-tui/internal/services"
 )
 
 // AppInterface defines what pages need from the app
@@ -15,6 +28,22 @@ type AppInterface interface {
 	SetInputCapture(fn func(event *tcell.EventKey) *tcell.EventKey)
 	SetRoot(root tview.Primitive, fullscreen bool)
 	RestorePages()
+	SetFocus(p tview.Primitive)
+	QueueUpdateDraw(f func())
+	Context() context.Context
+	Offline() bool
+	SetOffline(offline bool)
+	Client() *api.Client
+	Auth() *auth.Authenticator
+	Settings() *config.Settings
+	Cache() *cache.Cache
+	Library() *library.Library
+	Progress() *progress.Store
+	Sync() *readsync.Service
+	Downloader() *download.Downloader
+	Downloads() *download.Manager
+	PagePipeline() *services.PagePipeline
+	Images() *components.ImageLayer
 }
 
 // Page defines what the app needs from pages
@@ -23,3 +52,9 @@ type Page interface {
 	View() tview.Primitive
 	Init(AppInterface)
 }
+
+// Lifecycle is implemented by pages that need to know when they are shown or hidden
+type Lifecycle interface {
+	OnEnter()
+	OnLeave()
+}
@@ -1,8 +1,12 @@
 package pages
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+	"github.com/sangnt1552314/mangadex-tui/internal/graphics"
 	"github.com/sangnt1552314/mangadex-tui/internal/ui/interfaces"
 )
 
@@ -99,6 +103,52 @@ func (p *AboutPage) setupContent() tview.Primitive {
 			SetDynamicColors(true),
 		0, 1, false,
 	)
+	mainContent.AddItem(p.setupDiagnosticsView(), 9, 0, false)
 	mainContent.SetBorderPadding(1, 1, 2, 2)
 	return mainContent
 }
+
+// setupDiagnosticsView shows what the terminal was found to support, to
+// help when images do not show up right
+func (p *AboutPage) setupDiagnosticsView() tview.Primitive {
+	images := p.app.Images()
+	caps := images.Capabilities()
+
+	setting := p.app.Settings().ImageProtocol
+	if setting == "" {
+		setting = string(graphics.Auto)
+	}
+
+	var supported []string
+	for _, s := range []struct {
+		ok   bool
+		name graphics.Protocol
+	}{{caps.Kitty, graphics.Kitty}, {caps.Sixel, graphics.Sixel}, {caps.ITerm, graphics.ITerm}} {
+		if s.ok {
+			supported = append(supported, string(s.name))
+		}
+	}
+	if len(supported) == 0 {
+		supported = append(supported, "none")
+	}
+
+	diagnosticsView := tview.NewTextView().SetDynamicColors(true)
+	diagnosticsView.SetBackgroundColor(tcell.ColorBlack).
+		SetBorder(true).SetTitle("Diagnostics").SetTitleAlign(tview.AlignLeft)
+	fmt.Fprintf(diagnosticsView, "Terminal:       [white]%s[-]\n", tview.Escape(caps.Terminal))
+	fmt.Fprintf(diagnosticsView, "Images:         [white]%s[-] (setting: %s)\n", images.Protocol(), tview.Escape(setting))
+	fmt.Fprintf(diagnosticsView, "Supported:      [white]%s[-]\n", strings.Join(supported, ", "))
+	fmt.Fprintf(diagnosticsView, "Cell size:      [white]%dx%d px[-] (%s)\n", caps.CellSize.Width, caps.CellSize.Height, caps.CellSizeSource)
+	fmt.Fprintf(diagnosticsView, "True colour:    [white]%s[-]\n", yesNo(caps.TrueColor))
+	fmt.Fprintf(diagnosticsView, "tmux:           [white]%s[-]\n", yesNo(caps.Tmux))
+	fmt.Fprintf(diagnosticsView, "Terminal reply: [white]%s[-]", yesNo(caps.Answered))
+
+	return diagnosticsView
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
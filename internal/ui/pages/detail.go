@@ -1,25 +1,34 @@
 package pages
 
 import (
+	"context"
 	"fmt"
+	"image"
 	"log"
+	"strings"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 
-	"github.com/sangnt1552314/mangadex-tui/internal/api"
+	"github.com/sangnt1552314/mangadex-tui/internal/components"
+	"github.com/sangnt1552314/mangadex-tui/internal/library"
 	"github.com/sangnt1552314/mangadex-tui/internal/models"
 	"github.com/sangnt1552314/mangadex-tui/internal/services"
 	"github.com/sangnt1552314/mangadex-tui/internal/ui/interfaces"
 )
 
 type DetailPage struct {
-	app      interfaces.AppInterface
-	rootView *tview.Flex
-	manga    *models.Manga
-	limit    int
-	offset   int
-	total    int
+	app         interfaces.AppInterface
+	rootView    *tview.Flex
+	manga       *models.Manga
+	limit       int
+	offset      int
+	total       int
+	loader      *loader
+	chapterList *tview.Table
+	chapters    []models.Chapter
+	marked      map[string]models.Chapter
+	statusView  *tview.TextView
 }
 
 func NewDetailPage(app interfaces.AppInterface) *DetailPage {
@@ -30,6 +39,8 @@ func NewDetailPage(app interfaces.AppInterface) *DetailPage {
 		limit:    100,
 		offset:   0,
 		total:    0,
+		loader:   newLoader(app),
+		marked:   make(map[string]models.Chapter),
 	}
 }
 
@@ -43,9 +54,23 @@ func (p *DetailPage) View() tview.Primitive {
 
 func (p *DetailPage) SetManga(manga *models.Manga) {
 	p.manga = manga
+	p.offset = 0
+	p.marked = make(map[string]models.Chapter)
 	p.updateUI()
 }
 
+func (p *DetailPage) OnEnter() {
+	if p.loader.Stale() {
+		p.updateUI()
+		return
+	}
+	p.setChapterStatusCells()
+}
+
+func (p *DetailPage) OnLeave() {
+	p.loader.Cancel()
+}
+
 func (p *DetailPage) Init(app interfaces.AppInterface) {
 	p.app = app
 
@@ -67,7 +92,10 @@ func (p *DetailPage) Init(app interfaces.AppInterface) {
 }
 
 func (p *DetailPage) updateUI() {
+	p.loader.Reset()
 	p.rootView.Clear()
+	p.chapterList = nil
+	p.chapters = nil
 
 	p.rootView.SetDirection(tview.FlexRow).
 		SetBorder(false)
@@ -78,6 +106,29 @@ func (p *DetailPage) updateUI() {
 
 	p.rootView.AddItem(mainContent, 0, 1, false)
 	p.rootView.AddItem(menu, 3, 0, false)
+
+	p.pullReadMarkers()
+}
+
+// pullReadMarkers merges the account's read markers into local progress and
+// refreshes the chapter statuses if anything changed
+func (p *DetailPage) pullReadMarkers() {
+	if p.manga == nil || p.app.Offline() {
+		return
+	}
+
+	manga := *p.manga
+	fetchAsync(p.loader, func(ctx context.Context) (int, error) {
+		return p.app.Sync().Pull(ctx, manga)
+	}, func(changed int, err error) {
+		if err != nil {
+			log.Println("Error syncing read markers:", err)
+			return
+		}
+		if changed > 0 {
+			p.setChapterStatusCells()
+		}
+	})
 }
 
 func (p *DetailPage) setupMenu() tview.Primitive {
@@ -96,6 +147,18 @@ func (p *DetailPage) setupMenu() tview.Primitive {
 		p.app.SwitchToPage("search")
 	})
 
+	libraryButton := tview.NewButton("📚 Library")
+	libraryButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorOrange).Background(tcell.ColorBlack))
+	libraryButton.SetSelectedFunc(func() {
+		p.app.SwitchToPage("library")
+	})
+
+	downloadsButton := tview.NewButton("⬇ Downloads")
+	downloadsButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorDodgerBlue).Background(tcell.ColorBlack))
+	downloadsButton.SetSelectedFunc(func() {
+		p.app.SwitchToPage("downloads")
+	})
+
 	aboutButton := tview.NewButton("ℹ About")
 	aboutButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorGreen).Background(tcell.ColorBlack))
 	aboutButton.SetSelectedFunc(func() {
@@ -111,6 +174,8 @@ func (p *DetailPage) setupMenu() tview.Primitive {
 	// Add buttons to the flex container with equal proportion
 	menuFlex.AddItem(homeButton, 9, 1, false)
 	menuFlex.AddItem(searchButton, 9, 1, false)
+	menuFlex.AddItem(libraryButton, 10, 1, false)
+	menuFlex.AddItem(downloadsButton, 12, 1, false)
 	menuFlex.AddItem(aboutButton, 9, 1, false)
 	menuFlex.AddItem(exitButton, 9, 1, false)
 
@@ -126,10 +191,20 @@ func (p *DetailPage) setupMainContent() tview.Primitive {
 	mainContent := tview.NewFlex().SetDirection(tview.FlexColumn)
 	mainContent.SetBorder(false)
 
-	imageFlex := tview.NewImage()
-	if img := services.GetMangaImageByFilename(p.manga.ID, services.GetCoverFileName(*p.manga), 512); img != nil {
-		imageFlex.SetImage(img)
-		// imageFlex.SetAlign(tview.AlignCenter, tview.AlignCenter)
+	manga := p.manga
+	imageContainer := tview.NewFlex()
+	if p.app.Offline() {
+		imageContainer.AddItem(newOfflineView("Cover not available offline"), 0, 1, false)
+	} else {
+		load(p.loader, imageContainer, "Loading cover...", func(ctx context.Context) (image.Image, error) {
+			return services.GetMangaImageByFilename(ctx, p.app.Client(), manga.ID, services.GetCoverFileName(*manga), 512), nil
+		}, func(img image.Image, err error) {
+			imageFlex := components.NewImageView(p.app.Images())
+			if img != nil {
+				imageFlex.SetImage(img)
+			}
+			imageContainer.AddItem(imageFlex, 0, 1, false)
+		})
 	}
 
 	mangaDataFlex := tview.NewFlex().SetDirection(tview.FlexRow)
@@ -153,7 +228,7 @@ func (p *DetailPage) setupMainContent() tview.Primitive {
 	mangaDataFlex.AddItem(topMangaDataFlex, 0, 4, false)
 	mangaDataFlex.AddItem(bottomMangaDataFlex, 0, 6, false)
 
-	mainContent.AddItem(imageFlex, 0, 3, false)
+	mainContent.AddItem(imageContainer, 0, 3, false)
 	mainContent.AddItem(mangaDataFlex, 0, 7, false)
 
 	return mainContent
@@ -241,12 +316,52 @@ func (p *DetailPage) setupTopMangaDataFlex(flex *tview.Flex) {
 	leftFlex.AddItem(statusText, 0, 1, false)
 	leftFlex.AddItem(authorText, 0, 1, false)
 	leftFlex.AddItem(artistText, 0, 1, false)
+	leftFlex.AddItem(p.setupLibraryDropDown(), 1, 0, false)
 
 	rightFlex.AddItem(descText, 0, 1, false)
 	flex.AddItem(leftFlex, 0, 1, false)
 	flex.AddItem(rightFlex, 0, 2, false)
 }
 
+// setupLibraryDropDown lets the user save the manga to a library category
+func (p *DetailPage) setupLibraryDropDown() tview.Primitive {
+	lib := p.app.Library()
+	manga := p.manga
+
+	options := append([]string{"Not in library"}, lib.Categories()...)
+	current := 0
+	if entry, ok := lib.Get(manga.ID); ok {
+		for i, option := range options {
+			if option == entry.Category {
+				current = i
+			}
+		}
+	}
+
+	dropDown := tview.NewDropDown().SetLabel("Library: ")
+	dropDown.SetFieldBackgroundColor(tcell.ColorDarkSlateGray)
+	dropDown.SetOptions(options, nil)
+	dropDown.SetCurrentOption(current)
+	dropDown.SetSelectedFunc(func(option string, index int) {
+		var err error
+		if index == 0 {
+			err = lib.Remove(manga.ID)
+		} else {
+			err = lib.Add(*manga, option)
+			if err == nil && p.total > 0 {
+				err = lib.Update(manga.ID, func(entry *library.Entry) {
+					entry.ChapterCount = p.total
+				})
+			}
+		}
+		if err != nil {
+			log.Println("Error updating library:", err)
+		}
+	})
+
+	return dropDown
+}
+
 func (p *DetailPage) setupCategoryDataFlex(flex *tview.Flex) {
 	flex.SetDirection(tview.FlexRow)
 	flex.SetBorder(true).SetTitle("Categories").SetTitleAlign(tview.AlignLeft)
@@ -263,97 +378,268 @@ func (p *DetailPage) setupChapterDataFlex(flex *tview.Flex) {
 	flex.SetDirection(tview.FlexRow)
 	flex.SetBorder(true).SetTitle("Chapters").SetTitleAlign(tview.AlignLeft)
 
-	simpleChapterResp, err := api.GetChapterListResponse(models.ChapterQueryParams{
-		MangaId:            p.manga.ID,
-		Limit:              1,
-		Offset:             0,
-		TranslatedLanguage: []string{"en"},
-		Order: map[string]string{
-			"volume":  "asc",
-			"chapter": "asc",
-		},
-	})
-
-	if err != nil {
-		log.Println("Error fetching manga data:", err)
+	if p.app.Offline() {
+		p.buildOfflineChapterList(flex)
 		return
 	}
 
-	p.total = simpleChapterResp.Total
+	mangaID := p.manga.ID
+	load(p.loader, flex, "Loading chapters...", func(ctx context.Context) (*models.ChapterListResponse, error) {
+		return p.app.Client().GetChapterListResponse(ctx, models.ChapterQueryParams{
+			MangaId:            mangaID,
+			Limit:              1,
+			Offset:             0,
+			TranslatedLanguage: []string{"en"},
+			Order: []models.Order{
+				{Field: models.OrderByVolume, Direction: models.OrderAsc},
+				{Field: models.OrderByChapter, Direction: models.OrderAsc},
+			},
+		})
+	}, func(simpleChapterResp *models.ChapterListResponse, err error) {
+		if err != nil {
+			log.Println("Error fetching manga data:", err)
+			flex.AddItem(newErrorView("Failed to load chapters: "+errorMessage(err)), 0, 1, false)
+			return
+		}
+
+		p.total = simpleChapterResp.Total
+		if err := p.app.Library().Update(mangaID, func(entry *library.Entry) {
+			entry.ChapterCount = p.total
+		}); err != nil {
+			log.Println("Error updating library entry:", err)
+		}
+		p.buildChapterList(flex)
+	})
+}
 
+func (p *DetailPage) buildChapterList(flex *tview.Flex) {
 	params := models.ChapterQueryParams{
 		MangaId:            p.manga.ID,
 		Limit:              p.limit,
 		Offset:             p.offset,
 		TranslatedLanguage: []string{"en"},
-		Order: map[string]string{
-			"volume":  "asc",
-			"chapter": "asc",
+		Order: []models.Order{
+			{Field: models.OrderByVolume, Direction: models.OrderAsc},
+			{Field: models.OrderByChapter, Direction: models.OrderAsc},
 		},
+		// Group names are used to name downloaded chapters
+		Includes: []string{"scanlation_group"},
 	}
 
+	chapterListFlex := tview.NewFlex().SetDirection(tview.FlexRow)
 	chapterList := tview.NewTable().SetFixed(1, 0)
-	p.setChapterListData(chapterList, params)
+	p.setChapterListData(chapterListFlex, chapterList, params)
 
 	navigationFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
 	navigationFlex.SetBorder(false)
 	leftButton := tview.NewButton("◀ Previous")
+	continueButton := tview.NewButton("Continue reading")
 	rightButton := tview.NewButton("Next ▶")
 	leftButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorBlack))
+	continueButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorGreen).Background(tcell.ColorBlack))
 	rightButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorBlack))
+	continueButton.SetSelectedFunc(func() {
+		p.continueReading()
+	})
 	leftButton.SetSelectedFunc(func() {
 		if p.offset > 0 {
 			p.offset -= p.limit
 			params.Offset = p.offset
-			p.setChapterListData(chapterList, params)
+			p.setChapterListData(chapterListFlex, chapterList, params)
 		}
 		if p.offset < 0 {
 			p.offset = 0
 			params.Offset = p.offset
-			p.setChapterListData(chapterList, params)
+			p.setChapterListData(chapterListFlex, chapterList, params)
 		}
 		if p.offset >= p.total {
 			p.offset = p.total - p.limit
 			params.Offset = p.offset
-			p.setChapterListData(chapterList, params)
+			p.setChapterListData(chapterListFlex, chapterList, params)
 		}
 	})
 	rightButton.SetSelectedFunc(func() {
 		if p.offset+p.limit < p.total {
 			p.offset += p.limit
 			params.Offset = p.offset
-			p.setChapterListData(chapterList, params)
+			p.setChapterListData(chapterListFlex, chapterList, params)
 		}
 		if p.offset >= p.total {
 			p.offset = p.total - p.limit
 			params.Offset = p.offset
-			p.setChapterListData(chapterList, params)
+			p.setChapterListData(chapterListFlex, chapterList, params)
 		}
 		if p.offset < 0 {
 			p.offset = 0
 			params.Offset = p.offset
-			p.setChapterListData(chapterList, params)
+			p.setChapterListData(chapterListFlex, chapterList, params)
 		}
 	})
 	navigationFlex.AddItem(leftButton, 0, 1, false)
+	navigationFlex.AddItem(continueButton, 0, 1, false)
 	navigationFlex.AddItem(rightButton, 0, 1, false)
 
-	flex.AddItem(chapterList, 0, 1, false)
+	flex.AddItem(chapterListFlex, 0, 1, false)
 	flex.AddItem(navigationFlex, 1, 0, false)
+	flex.AddItem(p.setupDownloadFlex(), 1, 0, false)
+}
+
+// buildOfflineChapterList lists the downloaded chapters of the manga
+func (p *DetailPage) buildOfflineChapterList(flex *tview.Flex) {
+	flex.SetTitle("Chapters (downloaded)")
+
+	chapters := p.app.Downloader().Chapters(p.manga.ID)
+	if len(chapters) == 0 {
+		flex.AddItem(newErrorView("No downloaded chapters, go online to read this manga"), 0, 1, false)
+		return
+	}
+
+	chapterList := tview.NewTable().SetFixed(1, 0)
+	p.fillChapterList(chapterList, chapters)
 
+	continueButton := tview.NewButton("Continue reading")
+	continueButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorGreen).Background(tcell.ColorBlack))
+	continueButton.SetSelectedFunc(func() {
+		p.continueReading()
+	})
+
+	flex.AddItem(chapterList, 0, 1, false)
+	flex.AddItem(continueButton, 1, 0, false)
 }
 
-func (p *DetailPage) setChapterListData(list *tview.Table, params models.ChapterQueryParams) {
-	list.Clear()
+// setupDownloadFlex returns the row of download actions under the chapter list
+func (p *DetailPage) setupDownloadFlex() tview.Primitive {
+	downloadFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
 
-	p.setChapterListHeader(list)
+	selectedButton := tview.NewButton("⬇ Selected")
+	unreadButton := tview.NewButton("⬇ All unread")
+	selectedButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorDodgerBlue).Background(tcell.ColorBlack))
+	unreadButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorDodgerBlue).Background(tcell.ColorBlack))
+	selectedButton.SetSelectedFunc(func() {
+		p.downloadMarked()
+	})
+	unreadButton.SetSelectedFunc(func() {
+		exclude := p.app.Progress().CompletedIDs(p.manga.ID)
+		p.showDownloadResult(p.app.Downloads().EnqueueManga(*p.manga, exclude), "Queued all unread chapters")
+	})
+
+	rangeInput := tview.NewInputField().SetLabel(" Range: ").SetPlaceholder("1-20").SetFieldWidth(10)
+	rangeInput.SetFieldBackgroundColor(tcell.ColorDarkSlateGray)
+	rangeInput.SetDoneFunc(func(key tcell.Key) {
+		if key != tcell.KeyEnter {
+			return
+		}
+		from, to, ok := strings.Cut(rangeInput.GetText(), "-")
+		if !ok {
+			to = from
+		}
+		err := p.app.Downloads().EnqueueRange(*p.manga, strings.TrimSpace(from), strings.TrimSpace(to))
+		p.showDownloadResult(err, "Queued chapters "+rangeInput.GetText())
+		if err == nil {
+			rangeInput.SetText("")
+		}
+	})
+
+	p.statusView = tview.NewTextView().SetDynamicColors(true).
+		SetText(" [lightgrey]Space: select chapter  a: select page[-]")
+
+	downloadFlex.AddItem(selectedButton, 12, 0, false)
+	downloadFlex.AddItem(unreadButton, 14, 0, false)
+	downloadFlex.AddItem(rangeInput, 18, 0, false)
+	downloadFlex.AddItem(p.statusView, 0, 1, false)
+
+	return downloadFlex
+}
 
-	chapters, err := api.GetChapters(params)
+// markAll adds chapters to the download selection, or removes them if they
+// are all selected already
+func (p *DetailPage) markAll(chapters []models.Chapter) {
+	all := true
+	for _, chapter := range chapters {
+		if _, ok := p.marked[chapter.ID]; !ok {
+			all = false
+			break
+		}
+	}
+
+	for _, chapter := range chapters {
+		if all {
+			delete(p.marked, chapter.ID)
+		} else {
+			p.marked[chapter.ID] = chapter
+		}
+	}
+}
+
+// toggleMarked adds or removes a chapter from the download selection
+func (p *DetailPage) toggleMarked(chapter models.Chapter) {
+	if _, ok := p.marked[chapter.ID]; ok {
+		delete(p.marked, chapter.ID)
+	} else {
+		p.marked[chapter.ID] = chapter
+	}
+}
+
+// downloadMarked queues the selected chapters in list order
+func (p *DetailPage) downloadMarked() {
+	var chapters []models.Chapter
+	for _, chapter := range p.chapters {
+		if _, ok := p.marked[chapter.ID]; ok {
+			chapters = append(chapters, chapter)
+		}
+	}
+	// Chapters marked on other pages of the list go last
+	for id, chapter := range p.marked {
+		if !containsChapter(chapters, id) {
+			chapters = append(chapters, chapter)
+		}
+	}
+
+	err := p.app.Downloads().EnqueueChapters(*p.manga, chapters)
+	p.showDownloadResult(err, fmt.Sprintf("Queued %d chapters", len(chapters)))
+	if err == nil {
+		p.marked = make(map[string]models.Chapter)
+		p.setChapterStatusCells()
+	}
+}
 
+func (p *DetailPage) showDownloadResult(err error, message string) {
 	if err != nil {
-		log.Println("Error fetching manga data:", err)
+		log.Println("Error queueing download:", err)
+		p.statusView.SetText(fmt.Sprintf(" [red]%s[-]", tview.Escape(err.Error())))
 		return
 	}
+	p.statusView.SetText(fmt.Sprintf(" [green]%s[-]", tview.Escape(message)))
+}
+
+func containsChapter(chapters []models.Chapter, id string) bool {
+	for _, chapter := range chapters {
+		if chapter.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *DetailPage) setChapterListData(flex *tview.Flex, list *tview.Table, params models.ChapterQueryParams) {
+	load(p.loader, flex, "Loading chapters...", func(ctx context.Context) ([]models.Chapter, error) {
+		return p.app.Client().GetChapters(ctx, params)
+	}, func(chapters []models.Chapter, err error) {
+		if err != nil {
+			log.Println("Error fetching manga data:", err)
+			flex.AddItem(newErrorView("Failed to load chapters: "+errorMessage(err)), 0, 1, false)
+			return
+		}
+
+		p.fillChapterList(list, chapters)
+		flex.AddItem(list, 0, 1, false)
+	})
+}
+
+func (p *DetailPage) fillChapterList(list *tview.Table, chapters []models.Chapter) {
+	list.Clear()
+
+	p.setChapterListHeader(list)
 
 	for i, chapter := range chapters {
 		chapterCopy := chapter
@@ -363,8 +649,32 @@ func (p *DetailPage) setChapterListData(list *tview.Table, params models.Chapter
 		list.SetCell(i+1, 1, titleCell)
 	}
 
+	p.chapterList = list
+	p.chapters = chapters
+	p.setChapterStatusCells()
+
 	list.SetSelectable(true, false)
 
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() != tcell.KeyRune {
+			return event
+		}
+		switch event.Rune() {
+		case ' ':
+			row, _ := list.GetSelection()
+			if row > 0 && row <= len(chapters) {
+				p.toggleMarked(chapters[row-1])
+				p.setChapterStatusCells()
+			}
+			return nil
+		case 'a':
+			p.markAll(chapters)
+			p.setChapterStatusCells()
+			return nil
+		}
+		return event
+	})
+
 	list.SetSelectedFunc(func(row, column int) {
 		if row == 0 {
 			return // Skip header row
@@ -384,14 +694,73 @@ func (p *DetailPage) setChapterListData(list *tview.Table, params models.Chapter
 
 		selectedChapter := ref.(*models.Chapter)
 		if selectedChapter != nil {
-			readerPage := p.app.GetPageObject("reader").(*ReaderPage)
-			readerPage.SetData(p.manga, selectedChapter)
-			p.app.RestorePages()
-			p.app.SwitchToPage("reader")
+			p.openChapter(selectedChapter)
 		}
 	})
 }
 
+// setChapterStatusCells marks chapter rows as read or partially read,
+// selected for download and downloaded
+func (p *DetailPage) setChapterStatusCells() {
+	if p.chapterList == nil || p.manga == nil {
+		return
+	}
+
+	saved := p.app.Progress().ForManga(p.manga.ID)
+	for i, chapter := range p.chapters {
+		cell := tview.NewTableCell("").SetSelectable(true)
+		if progress, ok := saved[chapter.ID]; ok {
+			if progress.Completed {
+				cell.SetText("✓ Read").SetTextColor(tcell.ColorGreen)
+			} else {
+				cell.SetText(fmt.Sprintf("p. %d/%d", progress.Page+1, progress.Pages)).SetTextColor(tcell.ColorYellow)
+			}
+		}
+		p.chapterList.SetCell(i+1, 2, cell)
+
+		mark := "[ ]"
+		if _, ok := p.marked[chapter.ID]; ok {
+			mark = "[x]"
+		}
+		if p.app.Downloader().Exists(*p.manga, chapter) {
+			mark += " ⬇"
+		}
+		p.chapterList.SetCell(i+1, 3, tview.NewTableCell(tview.Escape(mark)).SetTextColor(tcell.ColorDodgerBlue))
+	}
+}
+
+// continueReading opens the most recently read chapter at its saved page,
+// or the chapter after it if it was finished
+func (p *DetailPage) continueReading() {
+	latest, ok := p.app.Progress().Latest(p.manga.ID)
+	if !ok {
+		if len(p.chapters) > 0 {
+			p.openChapter(&p.chapters[0])
+		}
+		return
+	}
+
+	chapter := latest.Chapter
+	if latest.Completed {
+		for i := range p.chapters {
+			if p.chapters[i].ID == latest.ChapterID() && i+1 < len(p.chapters) {
+				chapter = p.chapters[i+1]
+				break
+			}
+		}
+	}
+
+	p.openChapter(&chapter)
+}
+
+func (p *DetailPage) openChapter(chapter *models.Chapter) {
+	readerPage := p.app.GetPageObject("reader").(*ReaderPage)
+	readerPage.SetChapters(p.chapters)
+	readerPage.SetData(p.manga, chapter)
+	p.app.RestorePages()
+	p.app.SwitchToPage("reader")
+}
+
 func (p *DetailPage) setChapterListHeader(list *tview.Table) {
 	list.SetCell(0, 0, tview.NewTableCell("Chapter").
 		SetSelectable(false).
@@ -400,4 +769,12 @@ func (p *DetailPage) setChapterListHeader(list *tview.Table) {
 	list.SetCell(0, 1, tview.NewTableCell("Title").
 		SetSelectable(false).
 		SetTextColor(tcell.ColorYellow))
+
+	list.SetCell(0, 2, tview.NewTableCell("Progress").
+		SetSelectable(false).
+		SetTextColor(tcell.ColorGreen))
+
+	list.SetCell(0, 3, tview.NewTableCell("Download").
+		SetSelectable(false).
+		SetTextColor(tcell.ColorDodgerBlue))
 }
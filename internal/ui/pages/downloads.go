@@ -0,0 +1,296 @@
+package pages
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/download"
+	"github.com/sangnt1552314/mangadex-tui/internal/ui/interfaces"
+)
+
+type DownloadsPage struct {
+	app        interfaces.AppInterface
+	rootView   *tview.Flex
+	jobTable   *tview.Table
+	statusView *tview.TextView
+	redrawing  atomic.Bool
+}
+
+func NewDownloadsPage(app interfaces.AppInterface) *DownloadsPage {
+	return &DownloadsPage{
+		app:      app,
+		rootView: tview.NewFlex(),
+	}
+}
+
+func (p *DownloadsPage) Name() string {
+	return "downloads"
+}
+
+func (p *DownloadsPage) View() tview.Primitive {
+	return p.rootView
+}
+
+func (p *DownloadsPage) Init(app interfaces.AppInterface) {
+	p.app = app
+
+	// Functionalities
+	app.EnableMouse(true)
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyCtrlC:
+			app.Stop()
+			return nil
+		}
+		return event
+	})
+
+	// Layout
+	p.rootView.SetDirection(tview.FlexRow).
+		SetBorder(false)
+
+	// Layout - Main Content
+	mainContent := p.setupMainContent()
+
+	// Layout - Menu
+	menu := p.setupMenu()
+
+	// Add components to the root view
+	p.rootView.AddItem(mainContent, 0, 1, true)
+	p.rootView.AddItem(menu, 3, 0, false)
+}
+
+func (p *DownloadsPage) OnEnter() {
+	// Redraw live while the page is visible, coalescing bursts of progress
+	p.app.Downloads().SetChangedFunc(func() {
+		if p.redrawing.Swap(true) {
+			return
+		}
+		p.app.QueueUpdateDraw(func() {
+			p.redrawing.Store(false)
+			p.refreshJobs()
+		})
+	})
+
+	p.refreshJobs()
+	p.app.SetFocus(p.jobTable)
+}
+
+func (p *DownloadsPage) OnLeave() {
+	p.app.Downloads().SetChangedFunc(nil)
+}
+
+func (p *DownloadsPage) setupMenu() tview.Primitive {
+	menuFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
+	menuFlex.SetBackgroundColor(tcell.ColorBlack).SetBorder(true).SetTitle("Options").SetTitleAlign(tview.AlignLeft)
+
+	homeButton := tview.NewButton("⌂ Home")
+	homeButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorDodgerBlue).Background(tcell.ColorBlack))
+	homeButton.SetSelectedFunc(func() {
+		p.app.SwitchToPage("home")
+	})
+
+	libraryButton := tview.NewButton("📚 Library")
+	libraryButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorOrange).Background(tcell.ColorBlack))
+	libraryButton.SetSelectedFunc(func() {
+		p.app.SwitchToPage("library")
+	})
+
+	clearButton := tview.NewButton("Clear finished")
+	clearButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorBlack))
+	clearButton.SetSelectedFunc(func() {
+		p.clearFinished()
+	})
+
+	exitButton := tview.NewButton("⏻ Exit")
+	exitButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorRed).Background(tcell.ColorBlack))
+	exitButton.SetSelectedFunc(func() {
+		p.app.Stop()
+	})
+
+	// Add buttons to the flex container with equal proportion
+	menuFlex.AddItem(homeButton, 9, 1, false)
+	menuFlex.AddItem(libraryButton, 10, 1, false)
+	menuFlex.AddItem(clearButton, 16, 1, false)
+	menuFlex.AddItem(exitButton, 9, 1, false)
+
+	return menuFlex
+}
+
+func (p *DownloadsPage) setupMainContent() tview.Primitive {
+	mainContent := tview.NewFlex().SetDirection(tview.FlexRow)
+	mainContent.SetBorder(true).SetTitle("Downloads").SetTitleAlign(tview.AlignLeft)
+
+	p.jobTable = tview.NewTable().SetFixed(1, 0).SetSelectable(true, false)
+	p.jobTable.SetSelectionChangedFunc(func(row, column int) {
+		p.showJobError(row)
+	})
+	p.jobTable.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() != tcell.KeyRune {
+			return event
+		}
+
+		row, _ := p.jobTable.GetSelection()
+		job, ok := p.selectedJob(row)
+		switch event.Rune() {
+		case 'p':
+			if ok {
+				p.togglePause(job)
+			}
+			return nil
+		case 'c':
+			if ok {
+				p.apply(p.app.Downloads().Cancel(job.ID))
+			}
+			return nil
+		case 'x':
+			if ok {
+				p.apply(p.app.Downloads().Remove(job.ID))
+			}
+			return nil
+		case 'X':
+			p.clearFinished()
+			return nil
+		}
+		return event
+	})
+
+	p.statusView = tview.NewTextView().SetDynamicColors(true)
+
+	hint := tview.NewTextView().
+		SetText("p: pause/resume  c: cancel  x: remove  X: clear finished").
+		SetTextColor(tcell.ColorLightGrey)
+
+	mainContent.AddItem(p.jobTable, 0, 1, true)
+	mainContent.AddItem(p.statusView, 1, 0, false)
+	mainContent.AddItem(hint, 1, 0, false)
+
+	return mainContent
+}
+
+func (p *DownloadsPage) refreshJobs() {
+	table := p.jobTable
+	selected, _ := table.GetSelection()
+	table.Clear()
+
+	headers := []string{"Manga", "Download", "State", "Progress", "Size"}
+	for i, header := range headers {
+		table.SetCell(0, i, tview.NewTableCell(header).
+			SetSelectable(false).
+			SetTextColor(tcell.ColorYellow))
+	}
+
+	jobs := p.app.Downloads().Jobs()
+	if len(jobs) == 0 {
+		table.SetCell(1, 0, tview.NewTableCell("Nothing queued. Download chapters from a manga's detail page.").
+			SetSelectable(false).
+			SetTextColor(tcell.ColorLightGrey))
+		return
+	}
+
+	for i, job := range jobs {
+		jobCopy := job
+		table.SetCell(i+1, 0, tview.NewTableCell(job.Manga.Title()).SetReference(&jobCopy).SetMaxWidth(40).SetExpansion(1))
+		table.SetCell(i+1, 1, tview.NewTableCell(job.Label()))
+		table.SetCell(i+1, 2, tview.NewTableCell(string(job.State)).SetTextColor(jobStateColor(job.State)))
+		table.SetCell(i+1, 3, tview.NewTableCell(formatJobProgress(job)))
+		table.SetCell(i+1, 4, tview.NewTableCell(formatBytes(job.Bytes)).SetAlign(tview.AlignRight))
+	}
+
+	if selected < 1 {
+		selected = 1
+	}
+	if selected > len(jobs) {
+		selected = len(jobs)
+	}
+	table.Select(selected, 0)
+}
+
+// showJobError shows why the job at row failed, if it did
+func (p *DownloadsPage) showJobError(row int) {
+	job, ok := p.selectedJob(row)
+	if !ok || job.Error == "" {
+		p.statusView.SetText("")
+		return
+	}
+	p.statusView.SetText(fmt.Sprintf("[red]%s[-]", tview.Escape(job.Error)))
+}
+
+func (p *DownloadsPage) selectedJob(row int) (download.Job, bool) {
+	if row <= 0 {
+		return download.Job{}, false
+	}
+	job, ok := p.jobTable.GetCell(row, 0).GetReference().(*download.Job)
+	if !ok || job == nil {
+		return download.Job{}, false
+	}
+	return *job, true
+}
+
+func (p *DownloadsPage) togglePause(job download.Job) {
+	manager := p.app.Downloads()
+	if job.State == download.JobQueued || job.State == download.JobRunning {
+		p.apply(manager.Pause(job.ID))
+	} else {
+		p.apply(manager.Resume(job.ID))
+	}
+}
+
+func (p *DownloadsPage) clearFinished() {
+	p.apply(p.app.Downloads().ClearFinished())
+}
+
+// apply shows the outcome of a job action and redraws the queue
+func (p *DownloadsPage) apply(err error) {
+	p.refreshJobs()
+	if err != nil {
+		log.Println("Error updating download:", err)
+		p.statusView.SetText(fmt.Sprintf("[red]%s[-]", tview.Escape(err.Error())))
+	}
+}
+
+func jobStateColor(state download.JobState) tcell.Color {
+	switch state {
+	case download.JobRunning:
+		return tcell.ColorDodgerBlue
+	case download.JobDone:
+		return tcell.ColorGreen
+	case download.JobPaused:
+		return tcell.ColorYellow
+	case download.JobFailed, download.JobCancelled:
+		return tcell.ColorRed
+	default:
+		return tcell.ColorWhite
+	}
+}
+
+func formatJobProgress(job download.Job) string {
+	if !job.Resolved {
+		return "-"
+	}
+
+	progress := fmt.Sprintf("%d/%d chapters", len(job.Completed), len(job.Chapters))
+	if job.State == download.JobRunning && job.Pages > 0 {
+		progress += fmt.Sprintf(", page %d/%d", job.PagesDone, job.Pages)
+	}
+	return progress
+}
+
+// formatBytes shows a size in the largest unit that keeps it above one
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
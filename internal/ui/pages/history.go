@@ -0,0 +1,310 @@
+package pages
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/progress"
+	"github.com/sangnt1552314/mangadex-tui/internal/ui/interfaces"
+)
+
+type HistoryPage struct {
+	app        interfaces.AppInterface
+	rootView   *tview.Flex
+	query      string
+	entryTable *tview.Table
+}
+
+func NewHistoryPage(app interfaces.AppInterface) *HistoryPage {
+	return &HistoryPage{
+		app:      app,
+		rootView: tview.NewFlex(),
+	}
+}
+
+func (p *HistoryPage) Name() string {
+	return "history"
+}
+
+func (p *HistoryPage) View() tview.Primitive {
+	return p.rootView
+}
+
+func (p *HistoryPage) Init(app interfaces.AppInterface) {
+	p.app = app
+
+	// Functionalities
+	app.EnableMouse(true)
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyCtrlC:
+			app.Stop()
+			return nil
+		}
+		return event
+	})
+
+	// Layout
+	p.rootView.SetDirection(tview.FlexRow).
+		SetBorder(false)
+
+	// Layout - Main Content
+	mainContent := p.setupMainContent()
+
+	// Layout - Menu
+	menu := p.setupMenu()
+
+	// Add components to the root view
+	p.rootView.AddItem(mainContent, 0, 1, true)
+	p.rootView.AddItem(menu, 3, 0, false)
+}
+
+func (p *HistoryPage) OnEnter() {
+	p.refreshEntries()
+	p.app.SetFocus(p.entryTable)
+}
+
+func (p *HistoryPage) OnLeave() {}
+
+func (p *HistoryPage) setupMenu() tview.Primitive {
+	menuFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
+	menuFlex.SetBackgroundColor(tcell.ColorBlack).SetBorder(true).SetTitle("Options").SetTitleAlign(tview.AlignLeft)
+
+	homeButton := tview.NewButton("⌂ Home")
+	homeButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorDodgerBlue).Background(tcell.ColorBlack))
+	homeButton.SetSelectedFunc(func() {
+		p.app.SwitchToPage("home")
+	})
+
+	libraryButton := tview.NewButton("📚 Library")
+	libraryButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorOrange).Background(tcell.ColorBlack))
+	libraryButton.SetSelectedFunc(func() {
+		p.app.SwitchToPage("library")
+	})
+
+	clearButton := tview.NewButton("Clear all")
+	clearButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorBlack))
+	clearButton.SetSelectedFunc(func() {
+		p.confirmClear()
+	})
+
+	exitButton := tview.NewButton("⏻ Exit")
+	exitButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorRed).Background(tcell.ColorBlack))
+	exitButton.SetSelectedFunc(func() {
+		p.app.Stop()
+	})
+
+	// Add buttons to the flex container with equal proportion
+	menuFlex.AddItem(homeButton, 9, 1, false)
+	menuFlex.AddItem(libraryButton, 10, 1, false)
+	menuFlex.AddItem(clearButton, 11, 1, false)
+	menuFlex.AddItem(exitButton, 9, 1, false)
+
+	return menuFlex
+}
+
+func (p *HistoryPage) setupMainContent() tview.Primitive {
+	mainContent := tview.NewFlex().SetDirection(tview.FlexRow)
+	mainContent.SetBorder(true).SetTitle("History").SetTitleAlign(tview.AlignLeft)
+
+	searchInput := tview.NewInputField().SetLabel("Search: ")
+	searchInput.SetFieldBackgroundColor(tcell.ColorDarkSlateGray)
+	searchInput.SetChangedFunc(func(text string) {
+		p.query = text
+		p.refreshEntries()
+	})
+	searchInput.SetDoneFunc(func(key tcell.Key) {
+		p.app.SetFocus(p.entryTable)
+	})
+
+	p.entryTable = tview.NewTable().SetFixed(1, 0).SetSelectable(true, false)
+	p.entryTable.SetSelectedFunc(func(row, column int) {
+		if entry, ok := p.selectedEntry(row); ok {
+			p.resume(entry)
+		}
+	})
+	p.entryTable.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyTab {
+			p.app.SetFocus(searchInput)
+			return nil
+		}
+		if event.Key() != tcell.KeyRune {
+			return event
+		}
+
+		row, _ := p.entryTable.GetSelection()
+		entry, ok := p.selectedEntry(row)
+		switch event.Rune() {
+		case 'o':
+			if ok {
+				manga := entry.Manga
+				detailPage := p.app.GetPageObject("detail").(*DetailPage)
+				detailPage.SetManga(&manga)
+				p.app.SwitchToPage("detail")
+			}
+			return nil
+		case 'd':
+			if ok {
+				if err := p.app.Progress().RemoveHistory(entry.MangaID, entry.ChapterID()); err != nil {
+					log.Println("Error removing history entry:", err)
+				}
+				p.refreshEntries()
+				p.selectNear(row)
+			}
+			return nil
+		case 'C':
+			p.confirmClear()
+			return nil
+		}
+		return event
+	})
+
+	hint := tview.NewTextView().
+		SetText("Enter: resume  o: open manga  d: remove  C: clear all  Tab: search").
+		SetTextColor(tcell.ColorLightGrey)
+
+	mainContent.AddItem(searchInput, 1, 0, false)
+	mainContent.AddItem(p.entryTable, 0, 1, true)
+	mainContent.AddItem(hint, 1, 0, false)
+
+	return mainContent
+}
+
+func (p *HistoryPage) refreshEntries() {
+	table := p.entryTable
+	table.Clear()
+
+	table.SetCell(0, 0, tview.NewTableCell("Manga").
+		SetSelectable(false).
+		SetTextColor(tcell.ColorOrange))
+	table.SetCell(0, 1, tview.NewTableCell("Chapter").
+		SetSelectable(false).
+		SetTextColor(tcell.ColorYellow))
+	table.SetCell(0, 2, tview.NewTableCell("Page").
+		SetSelectable(false).
+		SetTextColor(tcell.ColorGreen))
+	table.SetCell(0, 3, tview.NewTableCell("Time").
+		SetSelectable(false).
+		SetTextColor(tcell.ColorLightCyan))
+
+	entries := p.app.Progress().History(p.query)
+	if len(entries) == 0 {
+		table.SetCell(1, 0, tview.NewTableCell("Nothing read yet").
+			SetSelectable(false).
+			SetTextColor(tcell.ColorLightGrey))
+		return
+	}
+
+	row := 1
+	day := ""
+	for _, entry := range entries {
+		// Group rows under a heading per day
+		if label := formatHistoryDay(entry.UpdatedAt); label != day {
+			day = label
+			table.SetCell(row, 0, tview.NewTableCell(day).
+				SetSelectable(false).
+				SetAttributes(tcell.AttrBold).
+				SetTextColor(tcell.ColorDodgerBlue))
+			row++
+		}
+
+		entryCopy := entry
+		page := "✓ Read"
+		if !entry.Completed {
+			page = fmt.Sprintf("%d/%d", entry.Page+1, entry.Pages)
+		}
+
+		table.SetCell(row, 0, tview.NewTableCell("  "+entry.Title()).SetReference(&entryCopy).SetMaxWidth(40).SetExpansion(1))
+		table.SetCell(row, 1, tview.NewTableCell(formatHistoryChapter(entry)))
+		table.SetCell(row, 2, tview.NewTableCell(page))
+		table.SetCell(row, 3, tview.NewTableCell(entry.UpdatedAt.Format("15:04")))
+		row++
+	}
+
+	table.Select(2, 0)
+	table.ScrollToBeginning()
+}
+
+func (p *HistoryPage) selectedEntry(row int) (progress.HistoryEntry, bool) {
+	if row <= 0 {
+		return progress.HistoryEntry{}, false
+	}
+	entry, ok := p.entryTable.GetCell(row, 0).GetReference().(*progress.HistoryEntry)
+	if !ok || entry == nil {
+		return progress.HistoryEntry{}, false
+	}
+	return *entry, true
+}
+
+// selectNear selects the entry at row, or the closest one before it when
+// row is a day heading or past the end
+func (p *HistoryPage) selectNear(row int) {
+	if row >= p.entryTable.GetRowCount() {
+		row = p.entryTable.GetRowCount() - 1
+	}
+	for ; row > 0; row-- {
+		if _, ok := p.selectedEntry(row); ok {
+			p.entryTable.Select(row, 0)
+			return
+		}
+	}
+}
+
+// resume reopens a chapter, at its saved page unless it was finished
+func (p *HistoryPage) resume(entry progress.HistoryEntry) {
+	manga := entry.Manga
+	chapter := entry.Chapter
+	readerPage := p.app.GetPageObject("reader").(*ReaderPage)
+	readerPage.SetData(&manga, &chapter)
+	p.app.RestorePages()
+	p.app.SwitchToPage("reader")
+}
+
+func (p *HistoryPage) confirmClear() {
+	modal := tview.NewModal().
+		SetText("Clear the whole reading history? Read chapters stay marked as read.").
+		SetBackgroundColor(tcell.ColorBlack).
+		AddButtons([]string{"Clear", "Cancel"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel == "Clear" {
+				if err := p.app.Progress().ClearHistory(); err != nil {
+					log.Println("Error clearing history:", err)
+				}
+				p.refreshEntries()
+			}
+			p.app.RestorePages()
+			p.app.SetFocus(p.entryTable)
+		})
+
+	p.app.SetRoot(modal, false)
+}
+
+// formatHistoryDay labels the day a chapter was read
+func formatHistoryDay(t time.Time) string {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch {
+	case !t.Before(today):
+		return "Today"
+	case !t.Before(today.AddDate(0, 0, -1)):
+		return "Yesterday"
+	default:
+		return t.Format("Monday, 2 January 2006")
+	}
+}
+
+func formatHistoryChapter(entry progress.HistoryEntry) string {
+	attributes := entry.Chapter.Attributes
+	label := "Oneshot"
+	if attributes.Chapter != "" {
+		label = "Ch. " + attributes.Chapter
+	}
+	if attributes.Title != "" {
+		label += " - " + attributes.Title
+	}
+	return label
+}
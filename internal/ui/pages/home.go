@@ -1,14 +1,16 @@
 package pages
 
 import (
+	"context"
 	"fmt"
+	"image"
 	"log"
 	"strconv"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 
-	"github.com/sangnt1552314/mangadex-tui/internal/api"
+	"github.com/sangnt1552314/mangadex-tui/internal/components"
 	"github.com/sangnt1552314/mangadex-tui/internal/models"
 	"github.com/sangnt1552314/mangadex-tui/internal/services"
 	"github.com/sangnt1552314/mangadex-tui/internal/ui/interfaces"
@@ -17,6 +19,8 @@ import (
 type HomePage struct {
 	app      interfaces.AppInterface
 	rootView *tview.Flex
+	loader   *loader
+	offline  bool
 }
 
 func NewHomePage(app interfaces.AppInterface) *HomePage {
@@ -24,6 +28,7 @@ func NewHomePage(app interfaces.AppInterface) *HomePage {
 	return &HomePage{
 		app:      app,
 		rootView: tview.NewFlex(),
+		loader:   newLoader(app),
 	}
 }
 
@@ -49,6 +54,24 @@ func (p *HomePage) Init(app interfaces.AppInterface) {
 		return event
 	})
 
+	p.updateUI()
+}
+
+func (p *HomePage) OnEnter() {
+	if p.loader.Stale() || p.offline != p.app.Offline() {
+		p.updateUI()
+	}
+}
+
+func (p *HomePage) OnLeave() {
+	p.loader.Cancel()
+}
+
+func (p *HomePage) updateUI() {
+	p.loader.Reset()
+	p.rootView.Clear()
+	p.offline = p.app.Offline()
+
 	// Layout
 	p.rootView.SetDirection(tview.FlexRow).
 		SetBorder(false)
@@ -74,6 +97,48 @@ func (p *HomePage) setupMenu() tview.Primitive {
 		p.app.SwitchToPage("search")
 	})
 
+	libraryButton := tview.NewButton("📚 Library")
+	libraryButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorOrange).Background(tcell.ColorBlack))
+	libraryButton.SetSelectedFunc(func() {
+		p.app.SwitchToPage("library")
+	})
+
+	historyButton := tview.NewButton("🕘 History")
+	historyButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorLightCyan).Background(tcell.ColorBlack))
+	historyButton.SetSelectedFunc(func() {
+		p.app.SwitchToPage("history")
+	})
+
+	downloadsButton := tview.NewButton("⬇ Downloads")
+	downloadsButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorDodgerBlue).Background(tcell.ColorBlack))
+	downloadsButton.SetSelectedFunc(func() {
+		p.app.SwitchToPage("downloads")
+	})
+
+	// Offline mode is switched on automatically when MangaDex can't be reached
+	modeLabel := "✈ Go offline"
+	if p.offline {
+		modeLabel = "⚡ Go online"
+	}
+	modeButton := tview.NewButton(modeLabel)
+	modeButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorBlack))
+	modeButton.SetSelectedFunc(func() {
+		p.app.SetOffline(!p.offline)
+		p.updateUI()
+	})
+
+	loginButton := tview.NewButton("👤 Login")
+	loginButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorOrange).Background(tcell.ColorBlack))
+	loginButton.SetSelectedFunc(func() {
+		p.app.SwitchToPage("login")
+	})
+
+	settingsButton := tview.NewButton("⚙ Settings")
+	settingsButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorLightGrey).Background(tcell.ColorBlack))
+	settingsButton.SetSelectedFunc(func() {
+		p.app.SwitchToPage("settings")
+	})
+
 	aboutButton := tview.NewButton("ℹ About")
 	aboutButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorGreen).Background(tcell.ColorBlack))
 	aboutButton.SetSelectedFunc(func() {
@@ -88,6 +153,12 @@ func (p *HomePage) setupMenu() tview.Primitive {
 
 	// Add buttons to the flex container with equal proportion
 	menuFlex.AddItem(searchButton, 9, 1, false)
+	menuFlex.AddItem(libraryButton, 10, 1, false)
+	menuFlex.AddItem(historyButton, 10, 1, false)
+	menuFlex.AddItem(downloadsButton, 12, 1, false)
+	menuFlex.AddItem(modeButton, 13, 1, false)
+	menuFlex.AddItem(loginButton, 9, 1, false)
+	menuFlex.AddItem(settingsButton, 12, 1, false)
 	menuFlex.AddItem(aboutButton, 9, 1, false)
 	menuFlex.AddItem(exitButton, 9, 1, false)
 
@@ -98,6 +169,13 @@ func (p *HomePage) setupMainContent() tview.Primitive {
 	mainContent := tview.NewFlex().SetDirection(tview.FlexRow)
 	mainContent.SetBorder(false).SetTitleAlign(tview.AlignLeft)
 
+	if p.offline {
+		mainContent.SetBorder(true).SetTitle("Offline")
+		mainContent.AddItem(newOfflineView("Offline mode: MangaDex is not contacted.\n"+
+			"Open your Library or History to read downloaded chapters."), 0, 1, false)
+		return mainContent
+	}
+
 	// Popular Flex
 	popularFlex := p.setupPoplarFlex(tview.NewFlex().SetDirection(tview.FlexRow))
 
@@ -116,8 +194,8 @@ func (p *HomePage) setupMainContent() tview.Primitive {
 	// Manga List Tables
 	featureParams := models.MangaQueryParams{
 		Limit: 9,
-		Order: map[string]string{
-			models.OrderByFollowCount: "desc",
+		Order: []models.Order{
+			{Field: models.OrderByFollowCount, Direction: models.OrderDesc},
 		},
 		Includes: []string{
 			"cover_art",
@@ -127,13 +205,12 @@ func (p *HomePage) setupMainContent() tview.Primitive {
 	}
 	featureMangaList := tview.NewTable()
 	p.setTableHeaderManga(featureMangaList)
-	featureFlex.AddItem(featureMangaList, 0, 1, false)
-	p.setMangaListData(featureMangaList, featureParams)
+	p.setMangaListData(featureFlex, featureMangaList, featureParams)
 
 	latestParams := models.MangaQueryParams{
 		Limit: 9,
-		Order: map[string]string{
-			models.OrderByCreatedAt: "desc",
+		Order: []models.Order{
+			{Field: models.OrderByCreatedAt, Direction: models.OrderDesc},
 		},
 		Includes: []string{
 			"cover_art",
@@ -143,8 +220,7 @@ func (p *HomePage) setupMainContent() tview.Primitive {
 	}
 	latestMangaList := tview.NewTable()
 	p.setTableHeaderManga(latestMangaList)
-	p.setMangaListData(latestMangaList, latestParams)
-	latestFlex.AddItem(latestMangaList, 0, 1, false)
+	p.setMangaListData(latestFlex, latestMangaList, latestParams)
 
 	//Setup Components
 	mainContent.AddItem(popularFlex, 0, 6, false)
@@ -156,8 +232,8 @@ func (p *HomePage) setupPoplarFlex(popularFlex *tview.Flex) tview.Primitive {
 	limit := 5
 	popularParams := models.MangaQueryParams{
 		Limit: limit,
-		Order: map[string]string{
-			models.OrderByRating: "desc",
+		Order: []models.Order{
+			{Field: models.OrderByRating, Direction: models.OrderDesc},
 		},
 		Includes: []string{
 			"cover_art",
@@ -166,15 +242,24 @@ func (p *HomePage) setupPoplarFlex(popularFlex *tview.Flex) tview.Primitive {
 		},
 	}
 
-	currentIndex := 0
-	popularManga, err := api.GetManga(popularParams)
+	popularFlex.SetBorder(true).SetTitle("Popular").SetTitleAlign(tview.AlignLeft)
 
-	if err != nil {
-		log.Println("Error fetching popular manga:", err)
-		return nil
-	}
+	load(p.loader, popularFlex, "Loading popular manga...", func(ctx context.Context) ([]models.Manga, error) {
+		return p.app.Client().GetManga(ctx, popularParams)
+	}, func(popularManga []models.Manga, err error) {
+		if err != nil {
+			log.Println("Error fetching popular manga:", err)
+			popularFlex.AddItem(newErrorView("Failed to load popular manga: "+errorMessage(err)), 0, 1, false)
+			return
+		}
+		p.buildPopularCarousel(popularFlex, popularManga)
+	})
 
-	popularFlex.SetBorder(true).SetTitle("Popular").SetTitleAlign(tview.AlignLeft)
+	return popularFlex
+}
+
+func (p *HomePage) buildPopularCarousel(popularFlex *tview.Flex, popularManga []models.Manga) {
+	currentIndex := 0
 
 	// Create a content area for popular manga
 	popularContent := tview.NewFlex().SetDirection(tview.FlexRow)
@@ -217,28 +302,31 @@ func (p *HomePage) setupPoplarFlex(popularFlex *tview.Flex) tview.Primitive {
 	// Add popular manga to the content area
 	popularFlex.AddItem(popularContent, 0, 1, false)
 	popularFlex.AddItem(popularNavigationFlex, 1, 0, false)
-
-	return popularFlex
 }
 
 func (p *HomePage) buildPopularContent(popularContent *tview.Flex, manga models.Manga) {
 	popularContent.Clear()
 
 	// Create a box for cover art placeholder
-	imageFlex := tview.NewImage()
-	// imageFlex.SetSize(30, 30)
+	imageContainer := tview.NewFlex()
 
 	// Get and set the image
 	coverFileName := services.GetCoverFileName(manga)
-	if img := services.GetMangaImageByFilename(manga.ID, coverFileName, 256); img != nil {
-		imageFlex.SetImage(img)
-	}
+	load(p.loader, imageContainer, "Loading cover...", func(ctx context.Context) (image.Image, error) {
+		return services.GetMangaImageByFilename(ctx, p.app.Client(), manga.ID, coverFileName, 256), nil
+	}, func(img image.Image, err error) {
+		imageFlex := components.NewImageView(p.app.Images())
+		if img != nil {
+			imageFlex.SetImage(img)
+		}
+		imageContainer.AddItem(imageFlex, 0, 1, false)
+	})
 
 	infoFlex := tview.NewFlex().SetDirection(tview.FlexRow)
 	infoFlex.SetBorder(true).SetTitle("Information").SetTitleAlign(tview.AlignLeft)
 
 	title := tview.NewTextView().
-		SetText(fmt.Sprintf("Title: %s", p.getMangaTitle(manga))).
+		SetText(fmt.Sprintf("Title: %s", manga.Title())).
 		SetTextColor(tcell.ColorOrange).
 		SetTextAlign(tview.AlignLeft).
 		SetDynamicColors(true)
@@ -268,7 +356,7 @@ func (p *HomePage) buildPopularContent(popularContent *tview.Flex, manga models.
 	infoFlex.AddItem(tagsView, 0, 2, false)
 	infoFlex.AddItem(description, 0, 4, false)
 
-	popularContent.AddItem(imageFlex, 0, 3, false)
+	popularContent.AddItem(imageContainer, 0, 3, false)
 	popularContent.AddItem(infoFlex, 0, 7, false)
 }
 
@@ -288,21 +376,39 @@ func (p *HomePage) setTableHeaderManga(mangaList *tview.Table) {
 	mangaList.SetFixed(1, 0)
 }
 
-func (p *HomePage) setMangaListData(mangaList *tview.Table, params models.MangaQueryParams) {
-	mangas, err := api.GetManga(params)
+func (p *HomePage) setMangaListData(flex *tview.Flex, mangaList *tview.Table, params models.MangaQueryParams) {
+	title := flex.GetTitle()
 
-	if err != nil {
-		log.Println("Error fetching manga data:", err)
-		return
-	}
+	load(p.loader, flex, "Loading manga...", func(ctx context.Context) (*models.Page[models.Manga], error) {
+		return p.app.Client().GetMangaPage(ctx, params)
+	}, func(page *models.Page[models.Manga], err error) {
+		if err != nil {
+			log.Println("Error fetching manga data:", err)
+			flex.AddItem(newErrorView("Failed to load manga: "+errorMessage(err)), 0, 1, false)
+			return
+		}
+
+		p.fillMangaList(flex, mangaList, params, page, title)
+		flex.AddItem(mangaList, 0, 1, false)
+	})
+}
 
-	for i, manga := range mangas {
+func (p *HomePage) fillMangaList(flex *tview.Flex, mangaList *tview.Table, params models.MangaQueryParams, page *models.Page[models.Manga], title string) {
+	mangas := newPager(p.loader, mangaList, func(ctx context.Context, offset, limit int) (*models.Page[models.Manga], error) {
+		next := params
+		next.Offset = offset
+		next.Limit = limit
+		return p.app.Client().GetMangaPage(ctx, next)
+	}, func(row int, manga models.Manga) {
 		mangaCopy := manga
-		titleCell := tview.NewTableCell(p.getMangaTitle(manga)).SetReference(&mangaCopy).SetMaxWidth(30)
-		mangaList.SetCell(i+1, 0, titleCell)
-		mangaList.SetCell(i+1, 1, p.formatTableStatus(manga.Attributes.Status))
-		mangaList.SetCell(i+1, 2, tview.NewTableCell(strconv.Itoa(manga.Attributes.Year)))
-	}
+		titleCell := tview.NewTableCell(manga.Title()).SetReference(&mangaCopy).SetMaxWidth(30)
+		mangaList.SetCell(row, 0, titleCell)
+		mangaList.SetCell(row, 1, formatTableStatus(manga.Attributes.Status))
+		mangaList.SetCell(row, 2, tview.NewTableCell(strconv.Itoa(manga.Attributes.Year)))
+	}, func(status string) {
+		flex.SetTitle(fmt.Sprintf("%s (%s)", title, status))
+	})
+	mangas.Append(page)
 
 	mangaList.SetSelectedFunc(func(row, column int) {
 		if row == 0 {
@@ -370,7 +476,7 @@ func (p *HomePage) showMangaDetailModal(manga *models.Manga) {
 	p.app.SetRoot(modal, false)
 }
 
-func (p *HomePage) formatTableStatus(status string) *tview.TableCell {
+func formatTableStatus(status string) *tview.TableCell {
 	switch status {
 	case "ongoing":
 		return tview.NewTableCell("Ongoing").SetTextColor(tcell.ColorGreen)
@@ -384,30 +490,3 @@ func (p *HomePage) formatTableStatus(status string) *tview.TableCell {
 		return tview.NewTableCell(status).SetTextColor(tcell.ColorWhite)
 	}
 }
-
-func (p *HomePage) getMangaTitle(manga models.Manga) string {
-	title := manga.Attributes.Title["en"]
-
-	var altTitle string
-	if len(manga.Attributes.AltTitles) > 0 {
-		for _, title := range manga.Attributes.AltTitles {
-			if engTitle, ok := title["en"]; ok {
-				altTitle = engTitle
-				break
-			}
-		}
-
-		if altTitle == "" && len(manga.Attributes.AltTitles) > 0 {
-			for _, val := range manga.Attributes.AltTitles[0] {
-				altTitle = val
-				break
-			}
-		}
-	}
-
-	if title == "" && altTitle != "" {
-		title = altTitle
-	}
-
-	return title
-}
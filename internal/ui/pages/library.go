@@ -0,0 +1,397 @@
+package pages
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log"
+	"strconv"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/components"
+	"github.com/sangnt1552314/mangadex-tui/internal/library"
+	"github.com/sangnt1552314/mangadex-tui/internal/services"
+	"github.com/sangnt1552314/mangadex-tui/internal/ui/interfaces"
+)
+
+var librarySortOptions = []struct {
+	label  string
+	sortBy library.SortBy
+}{
+	{"Title", library.SortByTitle},
+	{"Recently added", library.SortByRecentlyAdded},
+	{"Recently read", library.SortByRecentlyRead},
+	{"Unread", library.SortByUnread},
+}
+
+type LibraryPage struct {
+	app            interfaces.AppInterface
+	rootView       *tview.Flex
+	coverLoader    *loader
+	filter         library.Filter
+	categoryList   *tview.List
+	entryTable     *tview.Table
+	coverContainer *tview.Flex
+	infoView       *tview.TextView
+}
+
+func NewLibraryPage(app interfaces.AppInterface) *LibraryPage {
+	return &LibraryPage{
+		app:      app,
+		rootView: tview.NewFlex(),
+		// A new selection cancels the cover of the previous one
+		coverLoader: newLoader(app),
+	}
+}
+
+func (p *LibraryPage) Name() string {
+	return "library"
+}
+
+func (p *LibraryPage) View() tview.Primitive {
+	return p.rootView
+}
+
+func (p *LibraryPage) Init(app interfaces.AppInterface) {
+	p.app = app
+
+	// Functionalities
+	app.EnableMouse(true)
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyCtrlC:
+			app.Stop()
+			return nil
+		}
+		return event
+	})
+
+	// Layout
+	p.rootView.SetDirection(tview.FlexRow).
+		SetBorder(false)
+
+	// Layout - Main Content
+	mainContent := p.setupMainContent()
+
+	// Layout - Menu
+	menu := p.setupMenu()
+
+	// Add components to the root view
+	p.rootView.AddItem(mainContent, 0, 1, true)
+	p.rootView.AddItem(menu, 3, 0, false)
+}
+
+func (p *LibraryPage) OnEnter() {
+	p.refreshCategories()
+	p.refreshEntries()
+	p.app.SetFocus(p.entryTable)
+}
+
+func (p *LibraryPage) OnLeave() {
+	p.coverLoader.Cancel()
+}
+
+func (p *LibraryPage) setupMenu() tview.Primitive {
+	menuFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
+	menuFlex.SetBackgroundColor(tcell.ColorBlack).SetBorder(true).SetTitle("Options").SetTitleAlign(tview.AlignLeft)
+
+	homeButton := tview.NewButton("⌂ Home")
+	homeButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorDodgerBlue).Background(tcell.ColorBlack))
+	homeButton.SetSelectedFunc(func() {
+		p.app.SwitchToPage("home")
+	})
+
+	searchButton := tview.NewButton("🔍 Search")
+	searchButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorPurple).Background(tcell.ColorBlack))
+	searchButton.SetSelectedFunc(func() {
+		p.app.SwitchToPage("search")
+	})
+
+	exitButton := tview.NewButton("⏻ Exit")
+	exitButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorRed).Background(tcell.ColorBlack))
+	exitButton.SetSelectedFunc(func() {
+		p.app.Stop()
+	})
+
+	// Add buttons to the flex container with equal proportion
+	menuFlex.AddItem(homeButton, 9, 1, false)
+	menuFlex.AddItem(searchButton, 9, 1, false)
+	menuFlex.AddItem(exitButton, 9, 1, false)
+
+	return menuFlex
+}
+
+func (p *LibraryPage) setupMainContent() tview.Primitive {
+	mainContent := tview.NewFlex().SetDirection(tview.FlexColumn)
+	mainContent.SetBorder(false)
+
+	// Categories
+	categoryFlex := tview.NewFlex().SetDirection(tview.FlexRow)
+	categoryFlex.SetBorder(true).SetTitle("Categories").SetTitleAlign(tview.AlignLeft)
+
+	p.categoryList = tview.NewList().ShowSecondaryText(false)
+	p.categoryList.SetChangedFunc(func(index int, mainText, secondaryText string, shortcut rune) {
+		p.filter.Category = ""
+		if index > 0 {
+			p.filter.Category = mainText
+		}
+		p.refreshEntries()
+	})
+	p.categoryList.SetDoneFunc(func() {
+		p.app.SetFocus(p.entryTable)
+	})
+	p.categoryList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune && event.Rune() == 'd' {
+			if index := p.categoryList.GetCurrentItem(); index > 0 {
+				name, _ := p.categoryList.GetItemText(index)
+				p.confirmRemoveCategory(name)
+			}
+			return nil
+		}
+		return event
+	})
+
+	newCategory := tview.NewInputField().SetLabel("New: ")
+	newCategory.SetFieldBackgroundColor(tcell.ColorDarkSlateGray)
+	newCategory.SetDoneFunc(func(key tcell.Key) {
+		if key != tcell.KeyEnter {
+			return
+		}
+		if err := p.app.Library().AddCategory(newCategory.GetText()); err != nil {
+			log.Println("Error adding category:", err)
+			p.infoView.SetText(fmt.Sprintf("[red]%s[-]", tview.Escape(err.Error())))
+			return
+		}
+		newCategory.SetText("")
+		p.refreshCategories()
+	})
+
+	categoryHint := tview.NewTextView().
+		SetText("d: remove  Esc: back").
+		SetTextColor(tcell.ColorLightGrey)
+
+	categoryFlex.AddItem(p.categoryList, 0, 1, true)
+	categoryFlex.AddItem(newCategory, 1, 0, false)
+	categoryFlex.AddItem(categoryHint, 1, 0, false)
+
+	// Entries
+	entryFlex := tview.NewFlex().SetDirection(tview.FlexRow)
+	entryFlex.SetBorder(true).SetTitle("Library").SetTitleAlign(tview.AlignLeft)
+
+	toolbar := tview.NewFlex().SetDirection(tview.FlexColumn)
+	filterInput := tview.NewInputField().SetLabel("Filter: ")
+	filterInput.SetFieldBackgroundColor(tcell.ColorDarkSlateGray)
+	filterInput.SetChangedFunc(func(text string) {
+		p.filter.Query = text
+		p.refreshEntries()
+	})
+	sortLabels := make([]string, len(librarySortOptions))
+	for i, option := range librarySortOptions {
+		sortLabels[i] = option.label
+	}
+	sortDropDown := tview.NewDropDown().SetLabel(" Sort: ").SetOptions(sortLabels, func(option string, index int) {
+		p.filter.SortBy = librarySortOptions[index].sortBy
+		p.refreshEntries()
+	})
+	sortDropDown.SetFieldBackgroundColor(tcell.ColorDarkSlateGray)
+	sortDropDown.SetCurrentOption(0)
+	toolbar.AddItem(filterInput, 0, 1, false)
+	toolbar.AddItem(sortDropDown, 24, 0, false)
+
+	p.entryTable = tview.NewTable().SetFixed(1, 0).SetSelectable(true, false)
+	p.entryTable.SetSelectionChangedFunc(func(row, column int) {
+		if entry, ok := p.selectedEntry(row); ok {
+			p.showEntry(entry)
+		}
+	})
+	p.entryTable.SetSelectedFunc(func(row, column int) {
+		if entry, ok := p.selectedEntry(row); ok {
+			manga := entry.Manga
+			detailPage := p.app.GetPageObject("detail").(*DetailPage)
+			detailPage.SetManga(&manga)
+			p.app.SwitchToPage("detail")
+		}
+	})
+	p.entryTable.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch {
+		case event.Key() == tcell.KeyRune && event.Rune() == 'd':
+			row, _ := p.entryTable.GetSelection()
+			if entry, ok := p.selectedEntry(row); ok {
+				p.confirmRemove(entry)
+			}
+			return nil
+		case event.Key() == tcell.KeyTab:
+			p.app.SetFocus(p.categoryList)
+			return nil
+		}
+		return event
+	})
+
+	hint := tview.NewTextView().
+		SetText("Enter: open  d: remove  Tab: categories").
+		SetTextColor(tcell.ColorLightGrey)
+
+	entryFlex.AddItem(toolbar, 1, 0, false)
+	entryFlex.AddItem(p.entryTable, 0, 1, true)
+	entryFlex.AddItem(hint, 1, 0, false)
+
+	// Selected entry
+	previewFlex := tview.NewFlex().SetDirection(tview.FlexRow)
+	previewFlex.SetBorder(true).SetTitle("Details").SetTitleAlign(tview.AlignLeft)
+	p.coverContainer = tview.NewFlex()
+	p.infoView = tview.NewTextView().SetDynamicColors(true).SetWrap(true)
+	previewFlex.AddItem(p.coverContainer, 0, 2, false)
+	previewFlex.AddItem(p.infoView, 0, 1, false)
+
+	mainContent.AddItem(categoryFlex, 26, 0, false)
+	mainContent.AddItem(entryFlex, 0, 5, true)
+	mainContent.AddItem(previewFlex, 0, 2, false)
+
+	return mainContent
+}
+
+func (p *LibraryPage) refreshCategories() {
+	current := p.filter.Category
+
+	p.categoryList.Clear()
+	p.categoryList.AddItem("All", "", 0, nil)
+	selected := 0
+	for i, category := range p.app.Library().Categories() {
+		p.categoryList.AddItem(category, "", 0, nil)
+		if category == current {
+			selected = i + 1
+		}
+	}
+	p.categoryList.SetCurrentItem(selected)
+}
+
+func (p *LibraryPage) refreshEntries() {
+	if p.entryTable == nil {
+		return
+	}
+
+	table := p.entryTable
+	table.Clear()
+
+	table.SetCell(0, 0, tview.NewTableCell("Title").
+		SetSelectable(false).
+		SetTextColor(tcell.ColorOrange))
+	table.SetCell(0, 1, tview.NewTableCell("Category").
+		SetSelectable(false).
+		SetTextColor(tcell.ColorYellow))
+	table.SetCell(0, 2, tview.NewTableCell("Unread").
+		SetSelectable(false).
+		SetTextColor(tcell.ColorGreen))
+	table.SetCell(0, 3, tview.NewTableCell("Last read").
+		SetSelectable(false).
+		SetTextColor(tcell.ColorLightCyan))
+
+	entries := p.app.Library().Entries(p.filter)
+	for i, entry := range entries {
+		entryCopy := entry
+		lastRead := "-"
+		if entry.LastReadChapter != "" {
+			lastRead = "Ch. " + entry.LastReadChapter
+		}
+
+		table.SetCell(i+1, 0, tview.NewTableCell(entry.Title()).SetReference(&entryCopy).SetMaxWidth(40).SetExpansion(1))
+		table.SetCell(i+1, 1, tview.NewTableCell(entry.Category))
+		table.SetCell(i+1, 2, tview.NewTableCell(strconv.Itoa(entry.Unread())).SetAlign(tview.AlignRight))
+		table.SetCell(i+1, 3, tview.NewTableCell(lastRead))
+	}
+
+	if len(entries) == 0 {
+		p.coverLoader.Reset()
+		p.coverContainer.Clear()
+		p.infoView.SetText("[lightgrey]No manga here yet. Add some from a manga's detail page.[-]")
+		return
+	}
+	table.Select(1, 0)
+	table.ScrollToBeginning()
+}
+
+func (p *LibraryPage) selectedEntry(row int) (library.Entry, bool) {
+	if row <= 0 {
+		return library.Entry{}, false
+	}
+	cell := p.entryTable.GetCell(row, 0)
+	entry, ok := cell.GetReference().(*library.Entry)
+	if !ok || entry == nil {
+		return library.Entry{}, false
+	}
+	return *entry, true
+}
+
+func (p *LibraryPage) showEntry(entry library.Entry) {
+	manga := entry.Manga
+	p.infoView.SetText(fmt.Sprintf("[orange]%s[-]\nStatus: %s\nAdded: %s\nUnread: %d",
+		tview.Escape(entry.Title()),
+		services.FormatTextStatus(manga.Attributes.Status),
+		entry.AddedAt.Format("2006-01-02"),
+		entry.Unread()))
+
+	// Drop the cover of the previous selection if it is still loading
+	p.coverLoader.Reset()
+
+	if p.app.Offline() {
+		p.coverContainer.Clear()
+		p.coverContainer.AddItem(newOfflineView("Cover not available offline"), 0, 1, false)
+		return
+	}
+
+	load(p.coverLoader, p.coverContainer, "Loading cover...", func(ctx context.Context) (image.Image, error) {
+		return services.GetMangaImageByFilename(ctx, p.app.Client(), manga.ID, services.GetCoverFileName(manga), 256), nil
+	}, func(img image.Image, err error) {
+		imageView := components.NewImageView(p.app.Images())
+		if img != nil {
+			imageView.SetImage(img)
+		}
+		p.coverContainer.AddItem(imageView, 0, 1, false)
+	})
+}
+
+func (p *LibraryPage) confirmRemove(entry library.Entry) {
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("Remove %s from your library?", entry.Title())).
+		SetBackgroundColor(tcell.ColorBlack).
+		AddButtons([]string{"Remove", "Cancel"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel == "Remove" {
+				if err := p.app.Library().Remove(entry.Manga.ID); err != nil {
+					log.Println("Error removing library entry:", err)
+				}
+				p.refreshEntries()
+			}
+			p.app.RestorePages()
+			p.app.SetFocus(p.entryTable)
+		})
+
+	p.app.SetRoot(modal, false)
+}
+
+func (p *LibraryPage) confirmRemoveCategory(name string) {
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("Remove the category %s? Its manga move to %s.", name, library.CategoryPlanToRead)).
+		SetBackgroundColor(tcell.ColorBlack).
+		AddButtons([]string{"Remove", "Cancel"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel == "Remove" {
+				if err := p.app.Library().RemoveCategory(name); err != nil {
+					log.Println("Error removing category:", err)
+					p.infoView.SetText(fmt.Sprintf("[red]%s[-]", tview.Escape(err.Error())))
+				} else {
+					if p.filter.Category == name {
+						p.filter.Category = ""
+					}
+					p.refreshCategories()
+					p.refreshEntries()
+				}
+			}
+			p.app.RestorePages()
+			p.app.SetFocus(p.categoryList)
+		})
+
+	p.app.SetRoot(modal, false)
+}
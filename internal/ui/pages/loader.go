@@ -0,0 +1,186 @@
+package pages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/api"
+	"github.com/sangnt1552314/mangadex-tui/internal/auth"
+	"github.com/sangnt1552314/mangadex-tui/internal/components"
+	"github.com/sangnt1552314/mangadex-tui/internal/download"
+	"github.com/sangnt1552314/mangadex-tui/internal/ui/interfaces"
+)
+
+// loader runs page fetches off the tview event loop. Every fetch started
+// through a loader shares its context, so leaving the page cancels all of them.
+type loader struct {
+	app      interfaces.AppInterface
+	mu       sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	inFlight int
+	stale    bool
+}
+
+func newLoader(app interfaces.AppInterface) *loader {
+	l := &loader{app: app}
+	l.ctx, l.cancel = context.WithCancel(l.app.Context())
+	return l
+}
+
+// Reset cancels in-flight fetches and clears the stale flag. Pages call it
+// before rebuilding their content.
+func (l *loader) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cancel()
+	l.ctx, l.cancel = context.WithCancel(l.app.Context())
+	l.inFlight = 0
+	l.stale = false
+}
+
+// Cancel aborts in-flight fetches. If any were running the loader is marked
+// stale so the page knows to reload when it becomes visible again.
+func (l *loader) Cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.inFlight > 0 {
+		l.stale = true
+	}
+	l.cancel()
+	l.ctx, l.cancel = context.WithCancel(l.app.Context())
+	l.inFlight = 0
+}
+
+// Stale reports whether a fetch was cancelled before it could complete.
+func (l *loader) Stale() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.stale
+}
+
+func (l *loader) begin() context.Context {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight++
+	return l.ctx
+}
+
+func (l *loader) done(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if ctx == l.ctx && l.inFlight > 0 {
+		l.inFlight--
+	}
+}
+
+// throttleStatus describes the client's rate limit state for loading placeholders
+func (l *loader) throttleStatus() string {
+	state := l.app.Client().RateLimitState()
+	if !state.Throttled() {
+		return ""
+	}
+	return fmt.Sprintf("Throttled, retrying in %ds", int(math.Ceil(state.RetryIn().Seconds())))
+}
+
+// load shows a spinner inside target, runs fetch in a goroutine and then
+// calls apply on the event loop with the result. target is cleared before
+// apply runs. Results of cancelled fetches are dropped.
+func load[T any](l *loader, target *tview.Flex, label string, fetch func(ctx context.Context) (T, error), apply func(T, error)) {
+	spinner := components.NewSpinner(label).
+		SetStatusFunc(l.throttleStatus).
+		Start(l.app.QueueUpdateDraw)
+	target.Clear()
+	target.AddItem(spinner, 0, 1, false)
+
+	fetchAsync(l, func(ctx context.Context) (T, error) {
+		defer spinner.Stop()
+		return fetch(ctx)
+	}, func(result T, err error) {
+		target.Clear()
+		apply(result, err)
+	})
+}
+
+// fetchAsync runs fetch in a goroutine and calls apply on the event loop
+// with the result, without touching the layout. Results of cancelled
+// fetches are dropped. The returned context is the one passed to fetch.
+func fetchAsync[T any](l *loader, fetch func(ctx context.Context) (T, error), apply func(T, error)) context.Context {
+	ctx := l.begin()
+	go func() {
+		result, err := fetch(ctx)
+
+		l.app.QueueUpdateDraw(func() {
+			if ctx.Err() != nil {
+				return
+			}
+			l.done(ctx)
+
+			// Without a connection only local data can be shown
+			if api.IsNetworkError(err) {
+				l.app.SetOffline(true)
+			}
+
+			apply(result, err)
+		})
+	}()
+
+	return ctx
+}
+
+// newErrorView returns a text view used in place of content that failed to load
+func newErrorView(message string) *tview.TextView {
+	errorView := tview.NewTextView().
+		SetText(message).
+		SetTextColor(tcell.ColorRed).
+		SetTextAlign(tview.AlignCenter)
+	errorView.SetBackgroundColor(tcell.ColorBlack)
+	return errorView
+}
+
+// newOfflineView returns a text view used in place of content that needs a connection
+func newOfflineView(message string) *tview.TextView {
+	offlineView := tview.NewTextView().
+		SetText(message).
+		SetTextColor(tcell.ColorLightGrey).
+		SetTextAlign(tview.AlignCenter)
+	offlineView.SetBackgroundColor(tcell.ColorBlack)
+	return offlineView
+}
+
+// errorMessage turns a load error into a message the user can act on
+func errorMessage(err error) string {
+	switch {
+	case api.IsNotFound(err):
+		return "Not found on MangaDex"
+	case api.IsRateLimited(err):
+		return "MangaDex is rate limiting requests, try again in a moment"
+	case errors.Is(err, auth.ErrSessionExpired):
+		return "Your MangaDex session expired, please log in again"
+	case api.IsUnauthorized(err):
+		return "Not authorized, please log in again"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "Request timed out, check your connection"
+	case errors.Is(err, download.ErrNotDownloaded):
+		return "This chapter is not downloaded, go online to read it"
+	case api.IsNetworkError(err):
+		return "Can't reach MangaDex, switched to offline mode"
+	}
+
+	var apiErr *api.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Message()
+	}
+	return err.Error()
+}
@@ -0,0 +1,198 @@
+package pages
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/auth"
+	"github.com/sangnt1552314/mangadex-tui/internal/ui/interfaces"
+)
+
+type LoginPage struct {
+	app        interfaces.AppInterface
+	rootView   *tview.Flex
+	loader     *loader
+	form       *tview.Form
+	statusView *tview.TextView
+	creds      auth.Credentials
+}
+
+func NewLoginPage(app interfaces.AppInterface) *LoginPage {
+	return &LoginPage{
+		app:      app,
+		rootView: tview.NewFlex(),
+		loader:   newLoader(app),
+	}
+}
+
+func (p *LoginPage) Name() string {
+	return "login"
+}
+
+func (p *LoginPage) View() tview.Primitive {
+	return p.rootView
+}
+
+func (p *LoginPage) Init(app interfaces.AppInterface) {
+	p.app = app
+
+	// Functionalities
+	app.EnableMouse(true)
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyCtrlC:
+			app.Stop()
+			return nil
+		}
+		return event
+	})
+
+	// Layout
+	p.rootView.SetDirection(tview.FlexRow).
+		SetBorder(false)
+
+	// Layout - Main Content
+	mainContent := p.setupMainContent()
+
+	// Layout - Menu
+	menu := p.setupMenu()
+
+	// Add components to the root view
+	p.rootView.AddItem(mainContent, 0, 1, true)
+	p.rootView.AddItem(menu, 3, 0, false)
+}
+
+func (p *LoginPage) OnEnter() {
+	p.updateStatus()
+	p.app.SetFocus(p.form)
+}
+
+func (p *LoginPage) OnLeave() {
+	p.loader.Cancel()
+}
+
+func (p *LoginPage) setupMenu() tview.Primitive {
+	menuFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
+	menuFlex.SetBackgroundColor(tcell.ColorBlack).SetBorder(true).SetTitle("Options").SetTitleAlign(tview.AlignLeft)
+
+	homeButton := tview.NewButton("⌂ Home")
+	homeButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorDodgerBlue).Background(tcell.ColorBlack))
+	homeButton.SetSelectedFunc(func() {
+		p.app.SwitchToPage("home")
+	})
+
+	searchButton := tview.NewButton("🔍 Search")
+	searchButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorPurple).Background(tcell.ColorBlack))
+	searchButton.SetSelectedFunc(func() {
+		p.app.SwitchToPage("search")
+	})
+
+	exitButton := tview.NewButton("⏻ Exit")
+	exitButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorRed).Background(tcell.ColorBlack))
+	exitButton.SetSelectedFunc(func() {
+		p.app.Stop()
+	})
+
+	// Add buttons to the flex container with equal proportion
+	menuFlex.AddItem(homeButton, 9, 1, false)
+	menuFlex.AddItem(searchButton, 9, 1, false)
+	menuFlex.AddItem(exitButton, 9, 1, false)
+
+	return menuFlex
+}
+
+func (p *LoginPage) setupMainContent() tview.Primitive {
+	mainContent := tview.NewFlex().SetDirection(tview.FlexRow)
+	mainContent.SetBorder(true).SetTitle("MangaDex Account").SetTitleAlign(tview.AlignLeft)
+	mainContent.SetBorderPadding(1, 1, 2, 2)
+
+	help := tview.NewTextView().
+		SetText("Log in with a personal API client. Create one under Settings > API Clients on mangadex.org.\n" +
+			"The client ID, client secret and refresh token are saved in your user config directory, readable only by you. Your password is never saved.").
+		SetTextColor(tcell.ColorLightGrey).
+		SetWrap(true)
+
+	p.statusView = tview.NewTextView().SetDynamicColors(true)
+
+	form := tview.NewForm()
+	form.AddInputField("Client ID", "", 48, nil, func(text string) {
+		p.creds.ClientID = text
+	})
+	form.AddPasswordField("Client secret", "", 48, '*', func(text string) {
+		p.creds.ClientSecret = text
+	})
+	form.AddInputField("Username", "", 32, nil, func(text string) {
+		p.creds.Username = text
+	})
+	form.AddPasswordField("Password", "", 32, '*', func(text string) {
+		p.creds.Password = text
+	})
+	form.AddButton("Login", func() {
+		p.login()
+	})
+	form.AddButton("Logout", func() {
+		p.logout()
+	})
+	p.form = form
+
+	mainContent.AddItem(help, 3, 0, false)
+	mainContent.AddItem(p.statusView, 2, 0, false)
+	mainContent.AddItem(form, 0, 1, true)
+
+	p.updateStatus()
+
+	return mainContent
+}
+
+func (p *LoginPage) updateStatus() {
+	authenticator := p.app.Auth()
+	if authenticator.LoggedIn() {
+		p.statusView.SetText(fmt.Sprintf("[green]Logged in as %s[-]", tview.Escape(authenticator.Username())))
+	} else {
+		p.statusView.SetText("[yellow]Not logged in[-]")
+	}
+}
+
+func (p *LoginPage) login() {
+	creds := p.creds
+	if creds.ClientID == "" || creds.ClientSecret == "" || creds.Username == "" || creds.Password == "" {
+		p.statusView.SetText("[red]All fields are required[-]")
+		return
+	}
+
+	p.statusView.SetText("[yellow]Logging in...[-]")
+	fetchAsync(p.loader, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, p.app.Auth().Login(ctx, creds)
+	}, func(_ struct{}, err error) {
+		if err != nil {
+			log.Println("Error logging in:", err)
+			p.statusView.SetText(fmt.Sprintf("[red]%s[-]", tview.Escape(err.Error())))
+			return
+		}
+
+		// Drop the password from memory once it has been used
+		p.creds.Password = ""
+		if item, ok := p.form.GetFormItemByLabel("Password").(*tview.InputField); ok {
+			item.SetText("")
+		}
+		p.updateStatus()
+	})
+}
+
+func (p *LoginPage) logout() {
+	p.statusView.SetText("[yellow]Logging out...[-]")
+	fetchAsync(p.loader, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, p.app.Auth().Logout(ctx)
+	}, func(_ struct{}, err error) {
+		if err != nil {
+			log.Println("Error logging out:", err)
+			p.statusView.SetText(fmt.Sprintf("[red]%s[-]", tview.Escape(err.Error())))
+			return
+		}
+		p.updateStatus()
+	})
+}
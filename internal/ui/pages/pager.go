@@ -0,0 +1,114 @@
+package pages
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/rivo/tview"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+)
+
+const (
+	// pagerThreshold is how close to the last row the selection must be
+	// before the next page is requested
+	pagerThreshold = 5
+
+	// maxResultWindow is the largest offset+limit MangaDex allows
+	maxResultWindow = 10000
+)
+
+// pager appends pages of results to a table as the selection nears the
+// bottom. Row 0 is assumed to be a header.
+type pager[T any] struct {
+	loader    *loader
+	table     *tview.Table
+	fetch     func(ctx context.Context, offset, limit int) (*models.Page[T], error)
+	addRow    func(row int, item T)
+	setStatus func(status string)
+	loaded    int
+	total     int
+	limit     int
+	pending   context.Context
+}
+
+func newPager[T any](l *loader, table *tview.Table, fetch func(ctx context.Context, offset, limit int) (*models.Page[T], error), addRow func(row int, item T), setStatus func(status string)) *pager[T] {
+	pg := &pager[T]{
+		loader:    l,
+		table:     table,
+		fetch:     fetch,
+		addRow:    addRow,
+		setStatus: setStatus,
+	}
+
+	table.SetSelectionChangedFunc(func(row, column int) {
+		if row >= table.GetRowCount()-pagerThreshold {
+			pg.loadMore()
+		}
+	})
+
+	return pg
+}
+
+// Append adds a page of results to the table
+func (pg *pager[T]) Append(page *models.Page[T]) {
+	for _, item := range page.Items {
+		pg.addRow(pg.loaded+1, item)
+		pg.loaded++
+	}
+	pg.total = page.Total
+	if page.Limit > 0 {
+		pg.limit = page.Limit
+	} else {
+		pg.limit = max(pg.limit, len(page.Items))
+	}
+	pg.updateStatus()
+}
+
+func (pg *pager[T]) hasMore() bool {
+	return pg.loaded < pg.total && pg.loaded < maxResultWindow
+}
+
+// loading reports whether a page is being fetched. A fetch cancelled by
+// leaving the page no longer counts, so paging resumes on return.
+func (pg *pager[T]) loading() bool {
+	return pg.pending != nil && pg.pending.Err() == nil
+}
+
+func (pg *pager[T]) loadMore() {
+	if pg.loading() || !pg.hasMore() {
+		return
+	}
+
+	// Asking past the result window fails outright, so the last page is cut
+	// short instead
+	offset := pg.loaded
+	limit := min(max(pg.limit, 1), maxResultWindow-offset)
+	pg.pending = fetchAsync(pg.loader, func(ctx context.Context) (*models.Page[T], error) {
+		return pg.fetch(ctx, offset, limit)
+	}, func(page *models.Page[T], err error) {
+		pg.pending = nil
+		if err != nil {
+			log.Println("Error fetching next page:", err)
+			pg.updateStatus()
+			return
+		}
+		if len(page.Items) == 0 {
+			// The total shrank while paging, stop here
+			pg.total = pg.loaded
+			pg.updateStatus()
+			return
+		}
+		pg.Append(page)
+	})
+	pg.updateStatus()
+}
+
+func (pg *pager[T]) updateStatus() {
+	status := fmt.Sprintf("showing %d of %d", pg.loaded, pg.total)
+	if pg.loading() {
+		status += ", loading more..."
+	}
+	pg.setStatus(status)
+}
@@ -1,21 +1,42 @@
 package pages
 
 import (
+	"context"
+	"fmt"
 	"image"
+	"log"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+	"github.com/sangnt1552314/mangadex-tui/internal/components"
+	"github.com/sangnt1552314/mangadex-tui/internal/download"
+	"github.com/sangnt1552314/mangadex-tui/internal/library"
 	"github.com/sangnt1552314/mangadex-tui/internal/models"
-	"github.com/sangnt1552314/mangadex-tui/internal/services"
 	"github.com/sangnt1552314/mangadex-tui/internal/ui/interfaces"
 )
 
+// prefetchPages is how many pages of the next chapter are fetched ahead,
+// starting once the reader is within prefetchPages of the end
+const prefetchPages = 3
+
+// pageSource gives the reader the pages of a chapter one at a time
+type pageSource interface {
+	Count() int
+	Page(ctx context.Context, i int) (image.Image, error)
+}
+
 type ReaderPage struct {
-	app      interfaces.AppInterface
-	rootView *tview.Flex
-	manga    *models.Manga
-	chapter  *models.Chapter
-	images   []image.Image
+	app         interfaces.AppInterface
+	rootView    *tview.Flex
+	manga       *models.Manga
+	chapter     *models.Chapter
+	chapters    []models.Chapter
+	pages       pageSource
+	currentPage int
+	dataSaver   bool
+	prefetched  string
+	loader      *loader
 }
 
 func NewReaderPage(app interfaces.AppInterface) *ReaderPage {
@@ -24,7 +45,8 @@ func NewReaderPage(app interfaces.AppInterface) *ReaderPage {
 		rootView: tview.NewFlex(),
 		manga:    nil,
 		chapter:  nil,
-		images:   nil,
+		pages:    nil,
+		loader:   newLoader(app),
 	}
 }
 
@@ -37,11 +59,42 @@ func (p *ReaderPage) View() tview.Primitive {
 }
 
 func (p *ReaderPage) SetData(manga *models.Manga, chapter *models.Chapter) {
+	p.dataSaver = p.app.Settings().DataSaver
+	p.open(manga, chapter)
+}
+
+// open shows a chapter, keeping the reader's own settings
+func (p *ReaderPage) open(manga *models.Manga, chapter *models.Chapter) {
 	p.manga = manga
 	p.chapter = chapter
+	p.pages = nil
+	p.currentPage = 0
+
+	// Reopen unfinished chapters where they were left
+	if saved, ok := p.app.Progress().Get(manga.ID, chapter.ID); ok && !saved.Completed {
+		p.currentPage = saved.Page
+	}
+
 	p.updateUI()
 }
 
+// SetChapters gives the reader the chapter list the current chapter was
+// opened from, in reading order, so it can move on to the next one
+func (p *ReaderPage) SetChapters(chapters []models.Chapter) {
+	p.chapters = chapters
+}
+
+func (p *ReaderPage) OnEnter() {
+	if p.loader.Stale() {
+		p.updateUI()
+	}
+}
+
+func (p *ReaderPage) OnLeave() {
+	p.loader.Cancel()
+	p.app.PagePipeline().Close()
+}
+
 func (p *ReaderPage) Init(app interfaces.AppInterface) {
 	p.app = app
 
@@ -64,6 +117,8 @@ func (p *ReaderPage) updateUI() {
 		return
 	}
 
+	p.loader.Reset()
+	p.prefetched = ""
 	p.rootView.Clear()
 
 	// Layout
@@ -97,6 +152,18 @@ func (p *ReaderPage) setupMenu() tview.Primitive {
 		p.app.SwitchToPage("search")
 	})
 
+	// Data saver reads compressed images, which is all a terminal can show anyway
+	dataSaverLabel := "□ Data saver"
+	if p.dataSaver {
+		dataSaverLabel = "▣ Data saver"
+	}
+	dataSaverButton := tview.NewButton(dataSaverLabel)
+	dataSaverButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorLightCyan).Background(tcell.ColorBlack))
+	dataSaverButton.SetSelectedFunc(func() {
+		p.dataSaver = !p.dataSaver
+		p.updateUI()
+	})
+
 	aboutButton := tview.NewButton("ℹ About")
 	aboutButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorGreen).Background(tcell.ColorBlack))
 	aboutButton.SetSelectedFunc(func() {
@@ -111,6 +178,7 @@ func (p *ReaderPage) setupMenu() tview.Primitive {
 
 	menuFlex.AddItem(homeButton, 9, 1, false)
 	menuFlex.AddItem(searchButton, 9, 1, false)
+	menuFlex.AddItem(dataSaverButton, 14, 1, false)
 	menuFlex.AddItem(aboutButton, 9, 1, false)
 	menuFlex.AddItem(exitButton, 9, 1, false)
 
@@ -118,25 +186,55 @@ func (p *ReaderPage) setupMenu() tview.Primitive {
 }
 
 func (p *ReaderPage) setupMainContent() tview.Primitive {
-	currentImageIndex := 0
+	mainContent := tview.NewFlex().SetDirection(tview.FlexRow)
+	mainContent.SetBorder(true)
 
-	images, err := services.GetImagesByChapterId(p.chapter.ID)
-	p.images = images
+	manga, chapter := *p.manga, *p.chapter
+	load(p.loader, mainContent, "Loading chapter...", func(ctx context.Context) (pageSource, error) {
+		return p.openPages(ctx, manga, chapter)
+	}, func(pages pageSource, err error) {
+		if err != nil {
+			mainContent.AddItem(newErrorView("Error loading chapter images: "+errorMessage(err)), 0, 1, false)
+			return
+		}
+		if pages.Count() == 0 {
+			mainContent.AddItem(newErrorView("No pages available for this chapter"), 0, 1, false)
+			return
+		}
 
-	if err != nil {
-		mainContent := tview.NewTextView().
-			SetText("Error loading chapter images: " + err.Error()).
-			SetTextColor(tcell.ColorRed).
-			SetBackgroundColor(tcell.ColorBlack).
-			SetBorder(true)
-		return mainContent
+		p.pages = pages
+		p.buildPageViewer(mainContent)
+	})
+
+	return mainContent
+}
+
+// openPages reads downloaded chapters from their archive and streams
+// anything else from MangaDex, unless offline
+func (p *ReaderPage) openPages(ctx context.Context, manga models.Manga, chapter models.Chapter) (pageSource, error) {
+	downloader := p.app.Downloader()
+	if downloader.Exists(manga, chapter) {
+		archive, err := downloader.Pages(manga, chapter)
+		if err != nil {
+			return nil, err
+		}
+		return p.app.PagePipeline().OpenLocal(chapter.ID, archive), nil
+	}
+	if p.app.Offline() {
+		return nil, download.ErrNotDownloaded
 	}
+	return p.app.PagePipeline().Open(ctx, chapter.ID, p.dataSaver)
+}
 
-	mainContent := tview.NewFlex().SetDirection(tview.FlexRow)
-	mainContent.SetBorder(true)
+func (p *ReaderPage) buildPageViewer(mainContent *tview.Flex) {
+	if p.currentPage >= p.pages.Count() {
+		p.currentPage = p.pages.Count() - 1
+	}
 
-	imageFlex := tview.NewImage()
-	p.showImage(currentImageIndex, imageFlex)
+	pageView := tview.NewFlex()
+	imageFlex := components.NewImageView(p.app.Images())
+	imageFlex.SetBackgroundColor(tcell.ColorBlack)
+	pageView.AddItem(imageFlex, 0, 1, false)
 
 	navigationFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
 	navigationFlex.SetBorder(false)
@@ -145,36 +243,114 @@ func (p *ReaderPage) setupMainContent() tview.Primitive {
 	leftButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorBlack))
 	rightButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorBlack))
 	leftButton.SetSelectedFunc(func() {
-		currentImageIndex--
-		if currentImageIndex < 0 {
-			currentImageIndex = 0
+		if p.currentPage > 0 {
+			p.turnPage(p.currentPage-1, mainContent, pageView, imageFlex)
 		}
-		p.showImage(currentImageIndex, imageFlex)
 	})
 	rightButton.SetSelectedFunc(func() {
-		currentImageIndex++
-		if currentImageIndex >= len(images) {
-			currentImageIndex = len(images) - 1
+		if p.currentPage < p.pages.Count()-1 {
+			p.turnPage(p.currentPage+1, mainContent, pageView, imageFlex)
+			return
+		}
+		if next, ok := p.nextChapter(); ok {
+			p.open(p.manga, &next)
 		}
-		p.showImage(currentImageIndex, imageFlex)
 	})
 
 	navigationFlex.AddItem(leftButton, 0, 1, false)
 	navigationFlex.AddItem(rightButton, 0, 1, false)
 
-	mainContent.AddItem(imageFlex, 0, 1, false)
+	mainContent.AddItem(pageView, 0, 1, false)
 	mainContent.AddItem(navigationFlex, 1, 0, false)
 
-	return mainContent
+	p.turnPage(p.currentPage, mainContent, pageView, imageFlex)
+}
+
+// turnPage shows page i as soon as it has been fetched and records it as
+// the reading progress
+func (p *ReaderPage) turnPage(i int, mainContent, pageView *tview.Flex, imageFlex *components.ImageView) {
+	p.currentPage = i
+	p.setPageTitle(mainContent, " (loading)")
+	p.saveProgress()
+	p.prefetchNext()
+
+	pages := p.pages
+	fetchAsync(p.loader, func(ctx context.Context) (image.Image, error) {
+		return pages.Page(ctx, i)
+	}, func(img image.Image, err error) {
+		// The reader moved on while the page was loading
+		if p.currentPage != i {
+			return
+		}
+
+		p.setPageTitle(mainContent, "")
+		pageView.Clear()
+		if err != nil {
+			pageView.AddItem(newErrorView(fmt.Sprintf("Error loading page %d: %s", i+1, errorMessage(err))), 0, 1, false)
+			return
+		}
+		imageFlex.SetImage(img)
+		pageView.AddItem(imageFlex, 0, 1, false)
+	})
+}
+
+func (p *ReaderPage) setPageTitle(mainContent *tview.Flex, status string) {
+	mainContent.SetTitle(fmt.Sprintf(" Chapter %s - Page %d / %d%s ", p.chapter.Attributes.Chapter, p.currentPage+1, p.pages.Count(), status)).
+		SetTitleAlign(tview.AlignLeft)
 }
 
-func (p *ReaderPage) showImage(i int, imageFlex *tview.Image) {
-	image := p.images[i]
-	if image == nil {
-		imageFlex.SetImage(nil)
+// nextChapter returns the chapter after the current one in the list it was
+// opened from
+func (p *ReaderPage) nextChapter() (models.Chapter, bool) {
+	for i := range p.chapters {
+		if p.chapters[i].ID == p.chapter.ID && i+1 < len(p.chapters) {
+			return p.chapters[i+1], true
+		}
+	}
+	return models.Chapter{}, false
+}
+
+// prefetchNext fetches the first pages of the next chapter once the end of
+// the current one is near, so moving on does not wait on the network
+func (p *ReaderPage) prefetchNext() {
+	if p.currentPage < p.pages.Count()-prefetchPages || p.app.Offline() {
 		return
 	}
+	next, ok := p.nextChapter()
+	if !ok || next.ID == p.prefetched || p.app.Downloader().Exists(*p.manga, next) {
+		return
+	}
+	p.prefetched = next.ID
+
+	pipeline := p.app.PagePipeline()
+	ctx := p.app.Context()
+	dataSaver := p.dataSaver
+	go func() {
+		if err := pipeline.Prefetch(ctx, next.ID, dataSaver, prefetchPages); err != nil {
+			log.Printf("Error prefetching chapter %s: %v", next.ID, err)
+		}
+	}()
+}
 
-	imageFlex.SetImage(image)
-	imageFlex.SetBackgroundColor(tcell.ColorBlack)
+func (p *ReaderPage) saveProgress() {
+	store := p.app.Progress()
+	before, _ := store.Get(p.manga.ID, p.chapter.ID)
+	saved, err := store.Update(*p.manga, *p.chapter, p.currentPage, p.pages.Count())
+	if err != nil {
+		log.Println("Error saving reading progress:", err)
+		return
+	}
+	if saved.Completed && !before.Completed {
+		p.app.Sync().MarkRead(p.manga.ID, p.chapter.ID)
+	}
+
+	readCount := store.ReadCount(p.manga.ID)
+	chapterNumber := p.chapter.Attributes.Chapter
+	if err := p.app.Library().Update(p.manga.ID, func(entry *library.Entry) {
+		entry.ReadCount = readCount
+		entry.LastReadChapter = chapterNumber
+		entry.LastReadAt = time.Now()
+	}); err != nil {
+		log.Println("Error updating library entry:", err)
+	}
 }
@@ -1,20 +1,126 @@
 package pages
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/api"
+	"github.com/sangnt1552314/mangadex-tui/internal/models"
+	"github.com/sangnt1552314/mangadex-tui/internal/services"
 	"github.com/sangnt1552314/mangadex-tui/internal/ui/interfaces"
 )
 
+const searchLimit = 20
+
+// tagFilter is the state of a tag in the search filters
+type tagFilter int
+
+const (
+	tagIgnored tagFilter = iota
+	tagIncluded
+	tagExcluded
+)
+
+type searchOption struct {
+	label string
+	value string
+}
+
+var (
+	searchStatusOptions = []searchOption{
+		{"Any", ""},
+		{"Ongoing", "ongoing"},
+		{"Completed", "completed"},
+		{"Hiatus", "hiatus"},
+		{"Cancelled", "cancelled"},
+	}
+	searchDemographicOptions = []searchOption{
+		{"Any", ""},
+		{"Shounen", "shounen"},
+		{"Shoujo", "shoujo"},
+		{"Seinen", "seinen"},
+		{"Josei", "josei"},
+		{"None", "none"},
+	}
+	searchContentRatingOptions = []searchOption{
+		{"Any", ""},
+		{"Safe", "safe"},
+		{"Suggestive", "suggestive"},
+		{"Erotica", "erotica"},
+		{"Pornographic", "pornographic"},
+	}
+	searchTagsModeOptions = []searchOption{
+		{"AND", models.TagsModeAnd},
+		{"OR", models.TagsModeOr},
+	}
+	searchSortOptions = []struct {
+		label string
+		order models.Order
+	}{
+		{"Relevance", models.Order{Field: models.OrderByRelevance, Direction: models.OrderDesc}},
+		{"Latest upload", models.Order{Field: models.OrderByLatestUploadedChapter, Direction: models.OrderDesc}},
+		{"Most follows", models.Order{Field: models.OrderByFollowCount, Direction: models.OrderDesc}},
+		{"Best rating", models.Order{Field: models.OrderByRating, Direction: models.OrderDesc}},
+		{"Recently added", models.Order{Field: models.OrderByCreatedAt, Direction: models.OrderDesc}},
+		{"Title A-Z", models.Order{Field: models.OrderByTitle, Direction: models.OrderAsc}},
+		{"Year", models.Order{Field: models.OrderByYear, Direction: models.OrderDesc}},
+	}
+)
+
+// searchFilters holds the current values of the search form
+type searchFilters struct {
+	title              string
+	author             string
+	year               string
+	status             string
+	demographic        string
+	contentRating      string
+	originalLanguage   string
+	translatedLanguage string
+	includedTagsMode   string
+	excludedTagsMode   string
+	sort               int
+	tags               map[string]tagFilter
+}
+
 type SearchPage struct {
 	app      interfaces.AppInterface
 	rootView *tview.Flex
+	loader   *loader
+	// resultsLoader runs the search and its paging, so a new search can
+	// drop the previous one without touching the tags
+	resultsLoader *loader
+	filters       searchFilters
+	searchInput   *tview.InputField
+	filtersForm   *tview.Form
+	tagsFlex      *tview.Flex
+	tagsTable     *tview.Table
+	resultsFlex   *tview.Flex
+	resultsTable  *tview.Table
+	tagsLoaded    bool
+	searched      bool
 }
 
 func NewSearchPage(app interfaces.AppInterface) *SearchPage {
 	return &SearchPage{
-		app:      app,
-		rootView: tview.NewFlex(),
+		app:           app,
+		rootView:      tview.NewFlex(),
+		loader:        newLoader(app),
+		resultsLoader: newLoader(app),
+		filters: searchFilters{
+			translatedLanguage: "en",
+			includedTagsMode:   models.TagsModeAnd,
+			excludedTagsMode:   models.TagsModeOr,
+			tags:               make(map[string]tagFilter),
+		},
 	}
 }
 
@@ -55,6 +161,24 @@ func (p *SearchPage) Init(app interfaces.AppInterface) {
 	p.rootView.AddItem(menu, 3, 0, false)
 }
 
+func (p *SearchPage) OnEnter() {
+	if p.loader.Stale() {
+		p.loader.Reset()
+	}
+	if p.resultsLoader.Stale() && p.searched {
+		p.runSearch()
+	}
+	if !p.tagsLoaded {
+		p.loadTags()
+	}
+	p.app.SetFocus(p.searchInput)
+}
+
+func (p *SearchPage) OnLeave() {
+	p.loader.Cancel()
+	p.resultsLoader.Cancel()
+}
+
 func (p *SearchPage) setupMenu() tview.Primitive {
 	menuFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
 	menuFlex.SetBackgroundColor(tcell.ColorBlack).SetBorder(true).SetTitle("Options").SetTitleAlign(tview.AlignLeft)
@@ -92,7 +216,44 @@ func (p *SearchPage) setupMainContent() tview.Primitive {
 	// Search Component
 	searchBox := p.setInputSearchComponent()
 
-	mainContent.AddItem(searchBox, 0, 1, false)
+	// Filters, Tags & Results
+	bodyFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
+
+	filtersForm := p.setupFiltersForm()
+
+	p.tagsFlex = tview.NewFlex().SetDirection(tview.FlexRow)
+	p.tagsFlex.SetBorder(true).SetTitle("Tags (Enter: include/exclude)").SetTitleAlign(tview.AlignLeft)
+
+	p.resultsFlex = tview.NewFlex().SetDirection(tview.FlexRow)
+	p.resultsFlex.SetBorder(true).SetTitle("Results").SetTitleAlign(tview.AlignLeft)
+	p.resultsFlex.AddItem(tview.NewTextView().
+		SetText("Type a title and press Enter to search").
+		SetTextAlign(tview.AlignCenter).
+		SetTextColor(tcell.ColorLightGrey), 0, 1, false)
+
+	p.resultsTable = tview.NewTable().SetFixed(1, 0)
+	p.resultsTable.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyTab:
+			p.app.SetFocus(p.searchInput)
+			return nil
+		case tcell.KeyBacktab:
+			if p.tagsTable != nil {
+				p.app.SetFocus(p.tagsTable)
+			} else {
+				p.app.SetFocus(p.filtersForm)
+			}
+			return nil
+		}
+		return event
+	})
+
+	bodyFlex.AddItem(filtersForm, 44, 0, false)
+	bodyFlex.AddItem(p.tagsFlex, 34, 0, false)
+	bodyFlex.AddItem(p.resultsFlex, 0, 1, false)
+
+	mainContent.AddItem(searchBox, 3, 0, true)
+	mainContent.AddItem(bodyFlex, 0, 1, false)
 
 	return mainContent
 }
@@ -102,6 +263,355 @@ func (p *SearchPage) setInputSearchComponent() tview.Primitive {
 	search.SetTitle("Search").SetTitleAlign(tview.AlignLeft)
 	search.SetBorder(true)
 	search.SetFieldBackgroundColor(tcell.ColorNone).SetFieldTextColor(tcell.ColorWhite)
+	search.SetPlaceholder("Manga title...")
+	search.SetChangedFunc(func(text string) {
+		p.filters.title = text
+	})
+	search.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			p.runSearch()
+		case tcell.KeyTab:
+			p.app.SetFocus(p.filtersForm)
+		case tcell.KeyBacktab:
+			p.app.SetFocus(p.resultsTable)
+		}
+	})
 
+	p.searchInput = search
 	return search
 }
+
+func (p *SearchPage) setupFiltersForm() tview.Primitive {
+	form := tview.NewForm()
+	form.SetBorder(true).SetTitle("Filters").SetTitleAlign(tview.AlignLeft)
+	form.SetFieldBackgroundColor(tcell.ColorDarkSlateGray)
+	form.SetItemPadding(0)
+	form.SetCancelFunc(func() {
+		p.app.SetFocus(p.searchInput)
+	})
+
+	p.filtersForm = form
+	p.setFiltersFormItems()
+
+	return form
+}
+
+func (p *SearchPage) setFiltersFormItems() {
+	form := p.filtersForm
+
+	form.AddInputField("Author/Artist", "", 24, nil, func(text string) {
+		p.filters.author = text
+	})
+	form.AddInputField("Year", "", 6, tview.InputFieldInteger, func(text string) {
+		p.filters.year = text
+	})
+	form.AddDropDown("Status", searchOptionLabels(searchStatusOptions), 0, func(option string, index int) {
+		p.filters.status = searchStatusOptions[index].value
+	})
+	form.AddDropDown("Demographic", searchOptionLabels(searchDemographicOptions), 0, func(option string, index int) {
+		p.filters.demographic = searchDemographicOptions[index].value
+	})
+	form.AddDropDown("Content rating", searchOptionLabels(searchContentRatingOptions), 0, func(option string, index int) {
+		p.filters.contentRating = searchContentRatingOptions[index].value
+	})
+	form.AddInputField("Original lang", "", 16, nil, func(text string) {
+		p.filters.originalLanguage = text
+	})
+	form.AddInputField("Translated lang", p.filters.translatedLanguage, 16, nil, func(text string) {
+		p.filters.translatedLanguage = text
+	})
+	form.AddDropDown("Included tags", searchOptionLabels(searchTagsModeOptions), 0, func(option string, index int) {
+		p.filters.includedTagsMode = searchTagsModeOptions[index].value
+	})
+	form.AddDropDown("Excluded tags", searchOptionLabels(searchTagsModeOptions), 1, func(option string, index int) {
+		p.filters.excludedTagsMode = searchTagsModeOptions[index].value
+	})
+
+	sortLabels := make([]string, len(searchSortOptions))
+	for i, option := range searchSortOptions {
+		sortLabels[i] = option.label
+	}
+	form.AddDropDown("Sort by", sortLabels, 0, func(option string, index int) {
+		p.filters.sort = index
+	})
+
+	form.AddButton("Search", func() {
+		p.runSearch()
+	})
+	form.AddButton("Reset", func() {
+		p.resetFilters()
+	})
+}
+
+func (p *SearchPage) resetFilters() {
+	tags := p.filters.tags
+	for id := range tags {
+		delete(tags, id)
+	}
+
+	p.filtersForm.Clear(true)
+	p.filters = searchFilters{
+		title:              p.filters.title,
+		translatedLanguage: "en",
+		includedTagsMode:   models.TagsModeAnd,
+		excludedTagsMode:   models.TagsModeOr,
+		tags:               tags,
+	}
+	p.setFiltersFormItems()
+
+	if p.tagsTable != nil {
+		for row := 0; row < p.tagsTable.GetRowCount(); row++ {
+			p.renderTagCell(p.tagsTable.GetCell(row, 0))
+		}
+	}
+}
+
+func (p *SearchPage) loadTags() {
+	load(p.loader, p.tagsFlex, "Loading tags...", func(ctx context.Context) ([]models.Tag, error) {
+		return p.app.Client().GetTags(ctx)
+	}, func(tags []models.Tag, err error) {
+		if err != nil {
+			log.Println("Error fetching tags:", err)
+			p.tagsFlex.AddItem(newErrorView("Failed to load tags: "+errorMessage(err)), 0, 1, false)
+			return
+		}
+
+		p.tagsLoaded = true
+		p.tagsTable = p.buildTagsTable(tags)
+		p.tagsFlex.AddItem(p.tagsTable, 0, 1, false)
+	})
+}
+
+func (p *SearchPage) buildTagsTable(tags []models.Tag) *tview.Table {
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Attributes.Group != tags[j].Attributes.Group {
+			return tags[i].Attributes.Group < tags[j].Attributes.Group
+		}
+		return tags[i].Attributes.Name["en"] < tags[j].Attributes.Name["en"]
+	})
+
+	table := tview.NewTable()
+	for i, tag := range tags {
+		tagCopy := tag
+		cell := tview.NewTableCell("").SetReference(&tagCopy)
+		p.renderTagCell(cell)
+		table.SetCell(i, 0, cell)
+	}
+
+	table.SetSelectable(true, false)
+	table.SetSelectedFunc(func(row, column int) {
+		cell := table.GetCell(row, 0)
+		tag, ok := cell.GetReference().(*models.Tag)
+		if !ok {
+			return
+		}
+
+		// Cycle ignored -> included -> excluded
+		state := (p.filters.tags[tag.ID] + 1) % 3
+		if state == tagIgnored {
+			delete(p.filters.tags, tag.ID)
+		} else {
+			p.filters.tags[tag.ID] = state
+		}
+		p.renderTagCell(cell)
+	})
+	table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyTab:
+			p.app.SetFocus(p.resultsTable)
+			return nil
+		case tcell.KeyBacktab, tcell.KeyEscape:
+			p.app.SetFocus(p.filtersForm)
+			return nil
+		}
+		return event
+	})
+
+	return table
+}
+
+func (p *SearchPage) renderTagCell(cell *tview.TableCell) {
+	tag, ok := cell.GetReference().(*models.Tag)
+	if !ok {
+		return
+	}
+
+	name := tag.Attributes.Name["en"]
+	switch p.filters.tags[tag.ID] {
+	case tagIncluded:
+		cell.SetText("+ " + name).SetTextColor(tcell.ColorGreen)
+	case tagExcluded:
+		cell.SetText("- " + name).SetTextColor(tcell.ColorRed)
+	default:
+		cell.SetText("  " + name).SetTextColor(tcell.ColorWhite)
+	}
+}
+
+// buildParams turns the current filters into a manga query
+func (p *SearchPage) buildParams() models.MangaQueryParams {
+	f := p.filters
+
+	params := models.MangaQueryParams{
+		Limit:                       searchLimit,
+		Title:                       strings.TrimSpace(f.title),
+		OriginalLanguage:            splitList(f.originalLanguage),
+		AvailableTranslatedLanguage: splitList(f.translatedLanguage),
+		Includes: []string{
+			"cover_art",
+			"author",
+			"artist",
+		},
+	}
+
+	if year, err := strconv.Atoi(f.year); err == nil {
+		params.Year = year
+	}
+	if f.status != "" {
+		params.Status = []string{f.status}
+	}
+	if f.demographic != "" {
+		params.PublicationDemographic = []string{f.demographic}
+	}
+	if f.contentRating != "" {
+		params.ContentRating = []string{f.contentRating}
+	}
+
+	for id, state := range f.tags {
+		switch state {
+		case tagIncluded:
+			params.IncludedTags = append(params.IncludedTags, id)
+		case tagExcluded:
+			params.ExcludedTags = append(params.ExcludedTags, id)
+		}
+	}
+	sort.Strings(params.IncludedTags)
+	sort.Strings(params.ExcludedTags)
+	if len(params.IncludedTags) > 0 {
+		params.IncludedTagsMode = f.includedTagsMode
+	}
+	if len(params.ExcludedTags) > 0 {
+		params.ExcludedTagsMode = f.excludedTagsMode
+	}
+
+	// Relevance only makes sense when searching by title
+	order := searchSortOptions[f.sort].order
+	if order.Field != models.OrderByRelevance || params.Title != "" {
+		params.Order = []models.Order{order}
+	}
+
+	return params
+}
+
+func (p *SearchPage) runSearch() {
+	p.searched = true
+	params := p.buildParams()
+	author := strings.TrimSpace(p.filters.author)
+	p.resultsFlex.SetTitle("Results")
+
+	// The previous search and its pager must not land on top of this one
+	p.resultsLoader.Reset()
+	load(p.resultsLoader, p.resultsFlex, "Searching...", func(ctx context.Context) (*models.Page[models.Manga], error) {
+		client := p.app.Client()
+		if author != "" {
+			authors, err := client.SearchAuthors(ctx, author, 1)
+			if err != nil {
+				return nil, err
+			}
+			if len(authors) == 0 {
+				return nil, fmt.Errorf("no author or artist named %q: %w", author, api.ErrNoResults)
+			}
+			params.AuthorOrArtist = authors[0].ID
+		}
+		return client.GetMangaPage(ctx, params)
+	}, func(page *models.Page[models.Manga], err error) {
+		if errors.Is(err, api.ErrNoResults) || (err == nil && len(page.Items) == 0) {
+			p.resultsFlex.AddItem(tview.NewTextView().
+				SetText("No results").
+				SetTextAlign(tview.AlignCenter).
+				SetTextColor(tcell.ColorLightGrey), 0, 1, false)
+			return
+		}
+		if err != nil {
+			log.Println("Error searching manga:", err)
+			p.resultsFlex.AddItem(newErrorView("Search failed: "+errorMessage(err)), 0, 1, false)
+			return
+		}
+
+		p.setResultsData(params, page)
+		p.resultsFlex.AddItem(p.resultsTable, 0, 1, true)
+		p.app.SetFocus(p.resultsTable)
+	})
+}
+
+func (p *SearchPage) setResultsData(params models.MangaQueryParams, page *models.Page[models.Manga]) {
+	list := p.resultsTable
+	list.Clear()
+
+	list.SetCell(0, 0, tview.NewTableCell("Title").
+		SetSelectable(false).
+		SetTextColor(tcell.ColorOrange))
+	list.SetCell(0, 1, tview.NewTableCell("Status").
+		SetSelectable(false).
+		SetTextColor(tcell.ColorYellow))
+	list.SetCell(0, 2, tview.NewTableCell("Year").
+		SetSelectable(false).
+		SetTextColor(tcell.ColorDarkOrange))
+	list.SetCell(0, 3, tview.NewTableCell("Author").
+		SetSelectable(false).
+		SetTextColor(tcell.ColorLightCyan))
+
+	results := newPager(p.resultsLoader, list, func(ctx context.Context, offset, limit int) (*models.Page[models.Manga], error) {
+		next := params
+		next.Offset = offset
+		next.Limit = limit
+		return p.app.Client().GetMangaPage(ctx, next)
+	}, func(row int, manga models.Manga) {
+		mangaCopy := manga
+		list.SetCell(row, 0, tview.NewTableCell(manga.Title()).SetReference(&mangaCopy).SetMaxWidth(50).SetExpansion(1))
+		list.SetCell(row, 1, formatTableStatus(manga.Attributes.Status))
+		list.SetCell(row, 2, tview.NewTableCell(services.FormatTextYear(manga.Attributes.Year)))
+		list.SetCell(row, 3, tview.NewTableCell(services.GetAuthorName(manga)).SetMaxWidth(24))
+	}, func(status string) {
+		p.resultsFlex.SetTitle(fmt.Sprintf("Results (%s)", status))
+	})
+	results.Append(page)
+
+	list.SetSelectable(true, false)
+	list.Select(1, 0)
+	list.SetSelectedFunc(func(row, column int) {
+		if row == 0 {
+			return // Skip header row
+		}
+
+		selectedManga, ok := list.GetCell(row, 0).GetReference().(*models.Manga)
+		if !ok || selectedManga == nil {
+			log.Printf("Error: Invalid manga reference at row %d", row)
+			return
+		}
+
+		detailPage := p.app.GetPageObject("detail").(*DetailPage)
+		detailPage.SetManga(selectedManga)
+		p.app.RestorePages()
+		p.app.SwitchToPage("detail")
+	})
+}
+
+func searchOptionLabels(options []searchOption) []string {
+	labels := make([]string, len(options))
+	for i, option := range options {
+		labels[i] = option.label
+	}
+	return labels
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(text string) []string {
+	var items []string
+	for _, item := range strings.Split(text, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,189 @@
+package pages
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/sangnt1552314/mangadex-tui/internal/ui/interfaces"
+)
+
+type SettingsPage struct {
+	app         interfaces.AppInterface
+	rootView    *tview.Flex
+	cacheView   *tview.TextView
+	clearButton *tview.Button
+}
+
+func NewSettingsPage(app interfaces.AppInterface) *SettingsPage {
+	return &SettingsPage{
+		app:      app,
+		rootView: tview.NewFlex(),
+	}
+}
+
+func (p *SettingsPage) Name() string {
+	return "settings"
+}
+
+func (p *SettingsPage) View() tview.Primitive {
+	return p.rootView
+}
+
+func (p *SettingsPage) Init(app interfaces.AppInterface) {
+	p.app = app
+
+	// Functionalities
+	app.EnableMouse(true)
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyCtrlC:
+			app.Stop()
+			return nil
+		}
+		return event
+	})
+
+	// Layout
+	p.rootView.SetDirection(tview.FlexRow).
+		SetBorder(false)
+
+	// Layout - Main Content
+	mainContent := p.setupMainContent()
+
+	// Layout - Menu
+	menu := p.setupMenu()
+
+	// Add components to the root view
+	p.rootView.AddItem(mainContent, 0, 1, true)
+	p.rootView.AddItem(menu, 3, 0, false)
+}
+
+func (p *SettingsPage) OnEnter() {
+	p.refreshCacheUsage()
+	p.app.SetFocus(p.clearButton)
+}
+
+func (p *SettingsPage) OnLeave() {}
+
+func (p *SettingsPage) setupMenu() tview.Primitive {
+	menuFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
+	menuFlex.SetBackgroundColor(tcell.ColorBlack).SetBorder(true).SetTitle("Options").SetTitleAlign(tview.AlignLeft)
+
+	homeButton := tview.NewButton("⌂ Home")
+	homeButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorDodgerBlue).Background(tcell.ColorBlack))
+	homeButton.SetSelectedFunc(func() {
+		p.app.SwitchToPage("home")
+	})
+
+	exitButton := tview.NewButton("⏻ Exit")
+	exitButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorRed).Background(tcell.ColorBlack))
+	exitButton.SetSelectedFunc(func() {
+		p.app.Stop()
+	})
+
+	// Add buttons to the flex container with equal proportion
+	menuFlex.AddItem(homeButton, 9, 1, false)
+	menuFlex.AddItem(exitButton, 9, 1, false)
+
+	return menuFlex
+}
+
+func (p *SettingsPage) setupMainContent() tview.Primitive {
+	mainContent := tview.NewFlex().SetDirection(tview.FlexRow)
+	mainContent.SetBorder(true).SetTitle("Settings").SetTitleAlign(tview.AlignLeft)
+
+	mainContent.AddItem(p.setupReadingFlex(), 5, 0, false)
+	mainContent.AddItem(p.setupCacheFlex(), 6, 0, true)
+	mainContent.AddItem(p.setupDownloadFlex(), 5, 0, false)
+	mainContent.AddItem(tview.NewBox(), 0, 1, false)
+
+	return mainContent
+}
+
+func (p *SettingsPage) setupReadingFlex() tview.Primitive {
+	settings := p.app.Settings()
+
+	readingForm := tview.NewForm().SetItemPadding(0)
+	readingForm.SetBorder(true).SetTitle("Reading").SetTitleAlign(tview.AlignLeft)
+	readingForm.AddCheckbox("Data saver (compressed pages)", settings.DataSaver, func(checked bool) {
+		settings.DataSaver = checked
+		if err := settings.Save(); err != nil {
+			log.Println("Error saving settings:", err)
+		}
+	})
+	readingForm.AddCheckbox("Dither images on 256 colour terminals", settings.Dither, func(checked bool) {
+		settings.Dither = checked
+		p.app.Images().SetDither(checked)
+		if err := settings.Save(); err != nil {
+			log.Println("Error saving settings:", err)
+		}
+	})
+
+	return readingForm
+}
+
+func (p *SettingsPage) setupCacheFlex() tview.Primitive {
+	cacheFlex := tview.NewFlex().SetDirection(tview.FlexRow)
+	cacheFlex.SetBorder(true).SetTitle("Cache").SetTitleAlign(tview.AlignLeft)
+
+	p.cacheView = tview.NewTextView().SetDynamicColors(true)
+
+	p.clearButton = tview.NewButton("Clear cache")
+	p.clearButton.SetStyle(tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorBlack))
+	p.clearButton.SetSelectedFunc(func() {
+		p.confirmClearCache()
+	})
+
+	buttonFlex := tview.NewFlex().SetDirection(tview.FlexColumn)
+	buttonFlex.AddItem(p.clearButton, 15, 0, true)
+	buttonFlex.AddItem(tview.NewBox(), 0, 1, false)
+
+	cacheFlex.AddItem(p.cacheView, 0, 1, false)
+	cacheFlex.AddItem(buttonFlex, 1, 0, true)
+
+	return cacheFlex
+}
+
+func (p *SettingsPage) setupDownloadFlex() tview.Primitive {
+	settings := p.app.Settings()
+
+	downloadView := tview.NewTextView().SetDynamicColors(true)
+	downloadView.SetBorder(true).SetTitle("Downloads").SetTitleAlign(tview.AlignLeft)
+	fmt.Fprintf(downloadView, "Directory: [white]%s[-]\n", tview.Escape(settings.DownloadDir))
+	fmt.Fprintf(downloadView, "Layout:    [white]%s[-]\n", tview.Escape(settings.DownloadTemplate))
+	fmt.Fprintf(downloadView, "Workers:   [white]%d[-]", settings.DownloadWorkers)
+
+	return downloadView
+}
+
+// refreshCacheUsage shows how much of the disk cache is in use
+func (p *SettingsPage) refreshCacheUsage() {
+	usage := p.app.Cache().Usage()
+
+	p.cacheView.Clear()
+	fmt.Fprintf(p.cacheView, "Covers, pages and API listings kept on disk\n")
+	fmt.Fprintf(p.cacheView, "In use: [white]%s[-] of %s (%d entries)",
+		formatBytes(usage.Bytes), formatBytes(usage.MaxBytes), usage.Entries)
+}
+
+func (p *SettingsPage) confirmClearCache() {
+	modal := tview.NewModal().
+		SetText("Clear the cache? Covers and pages will be downloaded again when needed.").
+		SetBackgroundColor(tcell.ColorBlack).
+		AddButtons([]string{"Clear", "Cancel"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			if buttonLabel == "Clear" {
+				if err := p.app.Cache().Clear(); err != nil {
+					log.Println("Error clearing cache:", err)
+				}
+				p.refreshCacheUsage()
+			}
+			p.app.RestorePages()
+			p.app.SetFocus(p.clearButton)
+		})
+
+	p.app.SetRoot(modal, false)
+}